@@ -1,11 +1,13 @@
 package rls
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strings"
 )
 
-// CurrencyBalance represents a balance in a specific currency. Currently unused
+// CurrencyBalance represents a balance in a specific currency, in the currency's base unit (sats for BTC)
 type CurrencyBalance struct {
 	Currency     string `json:"currency"`
 	Amount       int64  `json:"amount"`
@@ -15,25 +17,115 @@ type CurrencyBalance struct {
 // Account contains the balances of an account
 type Account struct {
 	ID               string             `json:"id"`
-	Balance          int64              `json:"balance,omitempty"`
-	AvailableBalance int64              `json:"available_balance,omitempty"`
+	Balance          Amount             `json:"balance,omitempty"`
+	AvailableBalance Amount             `json:"available_balance,omitempty"`
 	CurrencyBalances []*CurrencyBalance `json:"currency_balances,omitempty"`
 }
 
 // GetReservedBalance returns the reserved balance of an account, calculated as Balance - AvailableBalance
-func (as *Account) GetReservedBalance() int64 {
+func (as *Account) GetReservedBalance() Amount {
 	return as.Balance - as.AvailableBalance
 }
 
+// Available returns the part of the balance that is not on hold
+func (cb *CurrencyBalance) Available() int64 {
+	return cb.Amount - cb.AmountOnHold
+}
+
+// Currency returns the balance of the account in currency. Currencies are matched case-insensitively.
+// If the account reports no per-currency balances, BTC falls back to Balance and AvailableBalance.
+func (as *Account) Currency(currency string) (*CurrencyBalance, bool) {
+	for _, cb := range as.CurrencyBalances {
+		if cb != nil && strings.EqualFold(cb.Currency, currency) {
+			return cb, true
+		}
+	}
+	if len(as.CurrencyBalances) == 0 && strings.EqualFold(currency, CurrencyBTC) {
+		return &CurrencyBalance{
+			Currency:     CurrencyBTC,
+			Amount:       as.Balance.Sats(),
+			AmountOnHold: as.GetReservedBalance().Sats(),
+		}, true
+	}
+	return nil, false
+}
+
+// Currencies returns the currencies the account holds a balance in
+func (as *Account) Currencies() []string {
+	if len(as.CurrencyBalances) == 0 {
+		return []string{CurrencyBTC}
+	}
+	currencies := make([]string, 0, len(as.CurrencyBalances))
+	for _, cb := range as.CurrencyBalances {
+		if cb != nil {
+			currencies = append(currencies, cb.Currency)
+		}
+	}
+	return currencies
+}
+
+// HasCurrency returns true if the account holds a balance in currency
+func (as *Account) HasCurrency(currency string) bool {
+	_, ok := as.Currency(currency)
+	return ok
+}
+
+// BalanceOf returns the balance of the account in currency, or 0 if the account does not hold it
+func (as *Account) BalanceOf(currency string) int64 {
+	if cb, ok := as.Currency(currency); ok {
+		return cb.Amount
+	}
+	return 0
+}
+
+// OnHoldOf returns the amount on hold in currency, or 0 if the account does not hold it
+func (as *Account) OnHoldOf(currency string) int64 {
+	if cb, ok := as.Currency(currency); ok {
+		return cb.AmountOnHold
+	}
+	return 0
+}
+
+// AvailableOf returns the balance of the account in currency that is not on hold, or 0 if the account does not hold it
+func (as *Account) AvailableOf(currency string) int64 {
+	if cb, ok := as.Currency(currency); ok {
+		return cb.Available()
+	}
+	return 0
+}
+
+// ValidateCurrency returns an error wrapping ErrUnsupportedCurrency if the account does not hold currency.
+// BTC is always accepted without a request.
+func (rls *RLSClient) ValidateCurrency(ctx context.Context, currency string) error {
+	if currency == "" || strings.EqualFold(currency, CurrencyBTC) {
+		return nil
+	}
+	acct, err := rls.GetAccountContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to validate currency %s : %w", currency, err)
+	}
+	if !acct.HasCurrency(currency) {
+		return fmt.Errorf("%w : %s (account holds %s)", ErrUnsupportedCurrency, currency, strings.Join(acct.Currencies(), ", "))
+	}
+	return nil
+}
+
 // GetAccount returns a  of the account's balance and available balance
+//
+// Deprecated: use GetAccountContext
 func (rls *RLSClient) GetAccount() (*Account, error) {
+	return rls.GetAccountContext(rls.context())
+}
+
+// GetAccountContext returns a summary of the account's balance and available balance
+func (rls *RLSClient) GetAccountContext(ctx context.Context) (*Account, error) {
 	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/accounts/%s", rls.BaseURL(), rls.AccountID()), nil)
 	if err != nil {
 		return nil, err
 	}
 
 	var acct Account
-	err = rls.sendRequest(req, &acct)
+	err = rls.sendRequest(ctx, OpGetAccount, req, &acct)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,77 @@
+package rls
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const multiCurrencyAccount = `{"id":"acct","currency_balances":[{"currency":"BTC","amount":1000,"amount_on_hold":100},{"currency":"USD","amount":5000,"amount_on_hold":0}]}`
+
+func TestAccountCurrencies(t *testing.T) {
+	var acct Account
+	if err := json.Unmarshal([]byte(multiCurrencyAccount), &acct); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(acct.Currencies(), []string{"BTC", "USD"}) {
+		t.Errorf("got %v", acct.Currencies())
+	}
+	if !acct.HasCurrency("usd") || acct.HasCurrency("EUR") {
+		t.Error("currencies not matched case-insensitively")
+	}
+	if acct.BalanceOf("BTC") != 1000 || acct.OnHoldOf("BTC") != 100 || acct.AvailableOf("BTC") != 900 || acct.AvailableOf("EUR") != 0 {
+		t.Errorf("unexpected balances %+v", acct)
+	}
+
+	legacy := Account{Balance: Sats(1000), AvailableBalance: Sats(700)}
+	if !reflect.DeepEqual(legacy.Currencies(), []string{CurrencyBTC}) || legacy.AvailableOf(CurrencyBTC) != 700 || legacy.OnHoldOf(CurrencyBTC) != 300 {
+		t.Errorf("BTC not derived from the legacy balance: %+v", legacy)
+	}
+}
+
+func TestCurrencyRequestsUseBaseUnits(t *testing.T) {
+	var bodies []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(multiCurrencyAccount))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.Write([]byte(`{"id":"x"}`))
+	})
+	ctx := context.Background()
+	if _, err := client.NewInvoiceWithCurrency(ctx, "USD", 1250, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.NewWithdrawalContext(ctx, NewWithdrawalWithCurrency("USD", 1250, "lnbc1", 10)); err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 2 || !strings.Contains(bodies[0], `"amount":1250,`) || !strings.Contains(bodies[0], `"currency":"USD"`) ||
+		!strings.Contains(bodies[1], `"amount":1250,`) || !strings.Contains(bodies[1], `"fee_limit":10}`) {
+		t.Errorf("amounts not sent in base units: %q", bodies)
+	}
+}
+
+func TestValidateCurrency(t *testing.T) {
+	calls := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(multiCurrencyAccount))
+	})
+	ctx := context.Background()
+	if err := client.ValidateCurrency(ctx, "btc"); err != nil || calls != 0 {
+		t.Errorf("BTC not accepted without a request: %v after %d calls", err, calls)
+	}
+	if err := client.ValidateCurrency(ctx, "USD"); err != nil {
+		t.Error(err)
+	}
+	if _, err := client.NewInvoiceWithCurrency(ctx, "EUR", 100, "", ""); !errors.Is(err, ErrUnsupportedCurrency) {
+		t.Errorf("expected an unsupported currency error, got %v", err)
+	}
+}
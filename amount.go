@@ -0,0 +1,196 @@
+package rls
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Amount is an amount of bitcoin with millisatoshi precision.
+// It is encoded in JSON as a number of sats, as used by the RLS API.
+type Amount int64
+
+// Amount units
+const (
+	MilliSatoshi Amount = 1
+	Satoshi             = 1000 * MilliSatoshi
+	Bit                 = 100 * Satoshi
+	Bitcoin             = 100000000 * Satoshi
+)
+
+// Unit is a denomination used to format an Amount
+type Unit int
+
+// Units an Amount can be formatted in
+const (
+	UnitSat Unit = iota
+	UnitMSat
+	UnitBits
+	UnitBTC
+)
+
+// unitInfo holds the size and suffix of each Unit
+var unitInfo = map[Unit]struct {
+	size   Amount
+	suffix string
+}{
+	UnitMSat: {MilliSatoshi, "msats"},
+	UnitSat:  {Satoshi, "sats"},
+	UnitBits: {Bit, "bits"},
+	UnitBTC:  {Bitcoin, "BTC"},
+}
+
+// amountUnits maps the unit suffixes accepted by ParseAmount to their size
+var amountUnits = map[string]Amount{
+	"":      Satoshi,
+	"sat":   Satoshi,
+	"sats":  Satoshi,
+	"msat":  MilliSatoshi,
+	"msats": MilliSatoshi,
+	"bit":   Bit,
+	"bits":  Bit,
+	"btc":   Bitcoin,
+	"k":     1000 * Satoshi,
+	"m":     1000000 * Satoshi,
+}
+
+var amountPattern = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)\s*([a-z]*)$`)
+
+// Sats returns an Amount of n sats
+func Sats(n int64) Amount {
+	return Amount(n) * Satoshi
+}
+
+// MSats returns an Amount of n msats
+func MSats(n int64) Amount {
+	return Amount(n)
+}
+
+// ParseAmount parses an amount such as "21000", "21000sat", "21k", "2100000msat", "210 bits" or "0.00021 BTC".
+// Numbers without a unit are sats, and the k and m suffixes stand for thousands and millions of sats.
+func ParseAmount(s string) (Amount, error) {
+	match := amountPattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(s)))
+	if match == nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	unit, ok := amountUnits[match[2]]
+	if !ok {
+		return 0, fmt.Errorf("invalid amount %q : unknown unit %q", s, match[2])
+	}
+	value, ok := new(big.Rat).SetString(match[1])
+	if !ok {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return amountFromRat(value.Mul(value, new(big.Rat).SetInt64(int64(unit))), s)
+}
+
+// amountFromRat converts a number of msats to an Amount, failing if it is fractional or too large
+func amountFromRat(msats *big.Rat, s string) (Amount, error) {
+	if !msats.IsInt() {
+		return 0, fmt.Errorf("invalid amount %q : more precise than 1 msat", s)
+	}
+	if !msats.Num().IsInt64() {
+		return 0, fmt.Errorf("invalid amount %q : out of range", s)
+	}
+	return Amount(msats.Num().Int64()), nil
+}
+
+// checkWholeSats returns an error wrapping ErrInvalidRequest if a, named name, is not a whole number of sats,
+// the precision of RLS API requests
+func checkWholeSats(name string, a Amount) error {
+	if a%Satoshi != 0 {
+		return fmt.Errorf("%w : invalid %s %s : the RLS API only accepts whole sats", ErrInvalidRequest, name, a)
+	}
+	return nil
+}
+
+// Sats returns the amount in sats, truncating msats
+func (a Amount) Sats() int64 {
+	return int64(a / Satoshi)
+}
+
+// MSats returns the amount in msats
+func (a Amount) MSats() int64 {
+	return int64(a)
+}
+
+// BTC returns the amount in BTC as a float, for display only
+func (a Amount) BTC() float64 {
+	return float64(a) / float64(Bitcoin)
+}
+
+// Add returns a + b
+func (a Amount) Add(b Amount) Amount {
+	return a + b
+}
+
+// Sub returns a - b
+func (a Amount) Sub(b Amount) Amount {
+	return a - b
+}
+
+// Mul returns a multiplied by n
+func (a Amount) Mul(n int64) Amount {
+	return a * Amount(n)
+}
+
+// MulFloat returns a multiplied by f, rounded down to the msat
+func (a Amount) MulFloat(f float64) Amount {
+	return Amount(float64(a) * f)
+}
+
+// Format returns the amount in unit with its suffix, e.g. "0.00021 BTC", without losing precision
+func (a Amount) Format(unit Unit) string {
+	return a.decimal(unit) + " " + unitInfo[unit].suffix
+}
+
+// decimal returns the amount in unit as an exact decimal number
+func (a Amount) decimal(unit Unit) string {
+	size := unitInfo[unit].size
+	if size == 0 {
+		size = Satoshi
+	}
+	sign := ""
+	if a < 0 {
+		sign = "-"
+		a = -a
+	}
+	whole := strconv.FormatInt(int64(a/size), 10)
+	rem := a % size
+	if rem == 0 {
+		return sign + whole
+	}
+	digits := len(strconv.FormatInt(int64(size), 10)) - 1
+	frac := fmt.Sprintf("%0*d", digits, int64(rem))
+	return sign + whole + "." + strings.TrimRight(frac, "0")
+}
+
+// String implements fmt.Stringer, formatting the amount in sats
+func (a Amount) String() string {
+	return a.Format(UnitSat)
+}
+
+// MarshalJSON encodes the amount as a number of sats
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.decimal(UnitSat)), nil
+}
+
+// UnmarshalJSON decodes a number of sats
+func (a *Amount) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	sats, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return fmt.Errorf("invalid amount %s", s)
+	}
+	amount, err := amountFromRat(sats.Mul(sats, new(big.Rat).SetInt64(int64(Satoshi))), s)
+	if err != nil {
+		return err
+	}
+	*a = amount
+	return nil
+}
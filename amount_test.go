@@ -0,0 +1,126 @@
+package rls
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Amount
+	}{
+		{"21000", Sats(21000)},
+		{"21000sat", Sats(21000)},
+		{"21000 sats", Sats(21000)},
+		{"21k", Sats(21000)},
+		{"2m", Sats(2000000)},
+		{"2100000msat", Sats(2100)},
+		{"1500 msats", MSats(1500)},
+		{"210 bits", Sats(21000)},
+		{"0.00021 BTC", Sats(21000)},
+		{"1.5", MSats(1500)},
+		{"0.001", MSats(1)},
+	}
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseAmount(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "-1", "abc", "1 sat sat", "10 eur", "0.0001", "1e3", "100000000000 btc"} {
+		if _, err := ParseAmount(in); err == nil {
+			t.Errorf("ParseAmount(%q): expected an error", in)
+		}
+	}
+}
+
+func TestAmountFormat(t *testing.T) {
+	a := MSats(2100001500)
+	tests := []struct {
+		unit Unit
+		want string
+	}{
+		{UnitSat, "2100001.5 sats"},
+		{UnitMSat, "2100001500 msats"},
+		{UnitBits, "21000.015 bits"},
+		{UnitBTC, "0.021000015 BTC"},
+	}
+	for _, tt := range tests {
+		if got := a.Format(tt.unit); got != tt.want {
+			t.Errorf("Format(%d) = %q, want %q", tt.unit, got, tt.want)
+		}
+	}
+	if got := Sats(-5).String(); got != "-5 sats" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	tests := []struct {
+		amount Amount
+		json   string
+	}{
+		{Sats(21000), "21000"},
+		{MSats(1500), "1.5"},
+		{0, "0"},
+		{Sats(-3), "-3"},
+	}
+	for _, tt := range tests {
+		b, err := json.Marshal(tt.amount)
+		if err != nil || string(b) != tt.json {
+			t.Errorf("Marshal(%d) = %s, %v, want %s", tt.amount, b, err, tt.json)
+		}
+		var got Amount
+		if err := json.Unmarshal([]byte(tt.json), &got); err != nil || got != tt.amount {
+			t.Errorf("Unmarshal(%s) = %d, %v, want %d", tt.json, got, err, tt.amount)
+		}
+	}
+
+	var a Amount
+	for _, in := range []string{`"100"`, `0.0001`, `1e30`} {
+		if err := json.Unmarshal([]byte(in), &a); err == nil {
+			t.Errorf("Unmarshal(%s): expected an error", in)
+		}
+	}
+}
+
+func TestSatsConstructors(t *testing.T) {
+	wd := NewWithdrawalWithFeeLimit(1000, "lnbc1", 10)
+	if wd.Amount != Sats(1000) || wd.FeeLimit() != Sats(10) {
+		t.Errorf("got amount %s, fee limit %s", wd.Amount, wd.FeeLimit())
+	}
+	if wd := NewWithdrawal(1000, "lnbc1"); wd.Amount != Sats(1000) {
+		t.Errorf("got amount %s", wd.Amount)
+	}
+	if req := NewInvoiceRequest(1000, "", ""); req.Amount != Sats(1000) {
+		t.Errorf("got amount %s", req.Amount)
+	}
+}
+
+func TestRejectsSubSatAmounts(t *testing.T) {
+	calls := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{}`))
+	})
+	ctx := context.Background()
+	if _, err := client.NewWithdrawalContext(ctx, NewWithdrawalFromAmount(MSats(1500), "lnbc1", Sats(10))); err == nil {
+		t.Error("expected an error for a fractional amount")
+	}
+	if _, err := client.NewWithdrawalContext(ctx, NewWithdrawalFromAmount(Sats(1000), "lnbc1", MSats(10500))); err == nil {
+		t.Error("expected an error for a fractional fee limit")
+	}
+	if _, err := client.NewInvoiceContext(ctx, MSats(1), "", ""); err == nil {
+		t.Error("expected an error for a fractional invoice amount")
+	}
+	if _, err := client.EstimateLightningFeeContext(ctx, "lnbc1", MSats(1500)); err == nil {
+		t.Error("expected an error for a fractional fee estimate amount")
+	}
+	if calls != 0 {
+		t.Errorf("%d requests sent", calls)
+	}
+}
@@ -0,0 +1,630 @@
+package rls
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DefaultBatchConcurrency is the number of rows PayBatch pays at once when no concurrency is given
+const DefaultBatchConcurrency = 4
+
+var (
+	// ErrBudgetExceeded is returned when paying a batch would exceed its budget
+	ErrBudgetExceeded = errors.New("rls: batch budget exceeded")
+	// ErrBatchStopped is returned when BatchOptions.Stop is closed before every row was started
+	ErrBatchStopped = errors.New("rls: batch stopped")
+)
+
+// BatchRow is a payment of a batch. Reference identifies the row and must be unique within the batch.
+type BatchRow struct {
+	Reference string
+	Invoice   string
+	// Amount is required for amountless invoices, and must be 0 or match the invoice otherwise
+	Amount Amount
+	// FeePolicy chooses the fee limit of the row. Defaults to BatchOptions.FeePolicy
+	FeePolicy FeePolicy
+}
+
+// BatchResult is the outcome of a row of a batch. A row with no WithdrawalID was not submitted.
+type BatchResult struct {
+	Reference    string
+	Invoice      string
+	Amount       Amount
+	FeeLimit     Amount
+	WithdrawalID string
+	State        WithdrawalState
+	FeePaid      Amount
+	Error        string
+}
+
+// Done returns true if the row's withdrawal has reached a terminal state, so it must not be paid again
+func (r BatchResult) Done() bool {
+	return r.State.IsTerminal()
+}
+
+// BatchOptions configures PayBatch
+type BatchOptions struct {
+	// Concurrency is the number of rows paid at once. Defaults to DefaultBatchConcurrency
+	Concurrency int
+	// Budget caps the sum of the amounts and fee limits of the batch, including rows paid by earlier runs.
+	// 0 disables it
+	Budget Amount
+	// FeePolicy chooses the fee limit of rows without their own. Defaults to DefaultFeePolicy
+	FeePolicy FeePolicy
+	// Previous are the results of an earlier run of the batch, e.g. read with ReadBatchResults. Rows that
+	// were submitted are not paid again: pending ones are polled instead. The last result of a reference wins.
+	Previous []BatchResult
+	// Wait waits for every withdrawal to succeed or fail, with WaitOptions, so results hold the fee paid
+	Wait        bool
+	WaitOptions WaitOptions
+	// OnResult, if set, is called with the result of every row as soon as it is known. Calls are serialized.
+	OnResult func(BatchResult)
+	// Stop, if set, stops starting rows once closed, e.g. on an interrupt. Unlike canceling the context,
+	// rows in flight run to completion and are reported, so their outcome is known.
+	Stop <-chan struct{}
+}
+
+// BatchRowError is the validation error of a row of a batch
+type BatchRowError struct {
+	// Row is the index of the row in the batch
+	Row       int
+	Reference string
+	Err       error
+}
+
+func (e *BatchRowError) Error() string {
+	return fmt.Sprintf("row %d (%s): %v", e.Row+1, e.Reference, e.Err)
+}
+
+func (e *BatchRowError) Unwrap() error {
+	return e.Err
+}
+
+// BatchValidationError lists the rows of a batch that failed validation
+type BatchValidationError struct {
+	Rows []*BatchRowError
+}
+
+func (e *BatchValidationError) Error() string {
+	errs := make([]string, len(e.Rows))
+	for i, row := range e.Rows {
+		errs[i] = row.Error()
+	}
+	return fmt.Sprintf("invalid batch : %s", strings.Join(errs, "; "))
+}
+
+// BatchReport summarizes a batch. Results are in the order of the rows.
+type BatchReport struct {
+	Results []BatchResult
+	// Paid and Fees are the amounts and fees of the successful withdrawals
+	Paid Amount
+	Fees Amount
+	// Succeeded, Failed and Pending count withdrawals by state. Errors counts rows that could not be
+	// submitted and NotStarted the rows left when the context was done.
+	Succeeded  int
+	Failed     int
+	Pending    int
+	Errors     int
+	NotStarted int
+}
+
+// ValidateBatch checks every row of a batch before anything is paid: references must be unique, invoices
+// must decode with an amount matching the row, and the amounts and fee limits of the rows left to pay must
+// fit in the budget and the available balance. It returns the planned result of every row, holding the
+// previous result of rows already submitted.
+func (rls *RLSClient) ValidateBatch(ctx context.Context, rows []BatchRow, opts BatchOptions) ([]BatchResult, error) {
+	previous := make(map[string]BatchResult, len(opts.Previous))
+	for _, result := range opts.Previous {
+		previous[result.Reference] = result
+	}
+	policy := opts.FeePolicy
+	if policy == nil {
+		policy = DefaultFeePolicy
+	}
+
+	plan := make([]BatchResult, len(rows))
+	rowErrs := make([]*BatchRowError, len(rows))
+	seen := make(map[string]bool, len(rows))
+	var toDecode []int
+	for i, row := range rows {
+		plan[i] = BatchResult{Reference: row.Reference, Invoice: row.Invoice, Amount: row.Amount}
+		switch {
+		case row.Reference == "":
+			rowErrs[i] = &BatchRowError{Row: i, Err: errors.New("missing reference")}
+		case seen[row.Reference]:
+			rowErrs[i] = &BatchRowError{Row: i, Reference: row.Reference, Err: errors.New("duplicate reference")}
+		case row.Invoice == "":
+			rowErrs[i] = &BatchRowError{Row: i, Reference: row.Reference, Err: errors.New("missing invoice")}
+		case row.Amount < 0:
+			rowErrs[i] = &BatchRowError{Row: i, Reference: row.Reference, Err: errors.New("negative amount")}
+		}
+		seen[row.Reference] = true
+		if rowErrs[i] != nil {
+			continue
+		}
+		if prev, ok := previous[row.Reference]; ok && prev.WithdrawalID != "" {
+			if prev.Invoice != row.Invoice {
+				rowErrs[i] = &BatchRowError{Row: i, Reference: row.Reference, Err: errors.New("invoice differs from the previous run")}
+				continue
+			}
+			plan[i] = prev
+			continue
+		}
+		toDecode = append(toDecode, i)
+	}
+
+	stopped := runConcurrently(ctx, opts.Stop, opts.Concurrency, toDecode, func(i int) {
+		decoded, err := rls.DecodeInvoiceContext(ctx, rows[i].Invoice)
+		switch {
+		case err != nil:
+		case decoded.Amount == 0 && rows[i].Amount == 0:
+			err = ErrAmountRequired
+		case decoded.Amount == 0:
+		case rows[i].Amount != 0 && rows[i].Amount != decoded.Amount:
+			err = fmt.Errorf("%w : invoice is for %s, not %s", ErrAmountMismatch, decoded.Amount, rows[i].Amount)
+		default:
+			plan[i].Amount = decoded.Amount
+		}
+		if err != nil {
+			rowErrs[i] = &BatchRowError{Row: i, Reference: rows[i].Reference, Err: err}
+			return
+		}
+		rowPolicy := rows[i].FeePolicy
+		if rowPolicy == nil {
+			rowPolicy = policy
+		}
+		plan[i].FeeLimit = rowPolicy.FeeLimit(plan[i].Amount)
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to validate batch : %w", err)
+	}
+	if stopped {
+		return nil, fmt.Errorf("failed to validate batch : %w", ErrBatchStopped)
+	}
+
+	var invalid BatchValidationError
+	for _, rowErr := range rowErrs {
+		if rowErr != nil {
+			invalid.Rows = append(invalid.Rows, rowErr)
+		}
+	}
+	if len(invalid.Rows) > 0 {
+		return plan, &invalid
+	}
+
+	var committed, remaining Amount
+	for _, result := range plan {
+		switch {
+		case result.WithdrawalID == "":
+			remaining += result.Amount + result.FeeLimit
+		case result.State.IsSuccess():
+			committed += result.Amount + result.FeePaid
+		case !result.Done():
+			committed += result.Amount + result.FeeLimit
+		}
+	}
+	if opts.Budget > 0 && committed+remaining > opts.Budget {
+		return plan, fmt.Errorf("invalid batch : %w : %s required, budget %s", ErrBudgetExceeded, committed+remaining, opts.Budget)
+	}
+	if remaining > 0 {
+		acct, err := rls.GetAccountContext(ctx)
+		if err != nil {
+			return plan, fmt.Errorf("failed to validate batch : %w", err)
+		}
+		if acct.AvailableBalance < remaining {
+			return plan, fmt.Errorf("invalid batch : %w : %s available, %s required with fee limits", ErrInsufficientFunds, acct.AvailableBalance, remaining)
+		}
+	}
+	return plan, nil
+}
+
+// PayBatch validates the batch with ValidateBatch, then pays its rows with Pay, opts.Concurrency at a time.
+// Every withdrawal is submitted with an idempotency key derived from its reference and invoice, so running
+// a batch again, with or without Previous, never pays a row twice. Rows submitted by an earlier run are
+// polled instead of paid, and rows that could not be submitted are tried again.
+// Nothing is paid if validation fails. The report is returned even on error.
+func (rls *RLSClient) PayBatch(ctx context.Context, rows []BatchRow, opts BatchOptions) (*BatchReport, error) {
+	plan, err := rls.ValidateBatch(ctx, rows, opts)
+	if err != nil {
+		return &BatchReport{Results: plan}, err
+	}
+
+	var mu sync.Mutex
+	report := func(i int, result BatchResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		plan[i] = result
+		if opts.OnResult != nil {
+			opts.OnResult(result)
+		}
+	}
+
+	var todo []int
+	for i, result := range plan {
+		if !result.Done() {
+			todo = append(todo, i)
+		}
+	}
+	started := make([]bool, len(plan))
+	stopped := runConcurrently(ctx, opts.Stop, opts.Concurrency, todo, func(i int) {
+		started[i] = true
+		if plan[i].WithdrawalID != "" {
+			report(i, rls.refreshBatchResult(ctx, plan[i], opts))
+			return
+		}
+		report(i, rls.payBatchRow(ctx, plan[i], opts))
+	})
+
+	summary := &BatchReport{Results: plan}
+	for i, result := range plan {
+		switch {
+		case result.State.IsSuccess():
+			summary.Succeeded++
+			summary.Paid += result.Amount
+			summary.Fees += result.FeePaid
+		case result.Done():
+			summary.Failed++
+		case result.WithdrawalID != "":
+			summary.Pending++
+		case !started[i]:
+			summary.NotStarted++
+		default:
+			summary.Errors++
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return summary, fmt.Errorf("failed to pay batch : %w", err)
+	}
+	if stopped {
+		return summary, fmt.Errorf("failed to pay batch : %w", ErrBatchStopped)
+	}
+	return summary, nil
+}
+
+// payBatchRow pays a planned row
+func (rls *RLSClient) payBatchRow(ctx context.Context, planned BatchResult, opts BatchOptions) BatchResult {
+	result := planned
+	result.Error = ""
+	paid, err := rls.Pay(ctx, planned.Invoice, PayOptions{
+		Amount:         planned.Amount,
+		FeePolicy:      AbsoluteFee(planned.FeeLimit),
+		IdempotencyKey: batchIdempotencyKey(planned.Reference, planned.Invoice),
+		Wait:           opts.Wait,
+		WaitOptions:    opts.WaitOptions,
+	})
+	if paid.Withdrawal != nil {
+		result.WithdrawalID = paid.Withdrawal.ID
+		result.State = paid.Withdrawal.State
+		result.FeePaid = paid.Withdrawal.FeePaid
+	}
+	if err != nil && !errors.Is(err, ErrWithdrawalFailed) {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// refreshBatchResult updates the result of a row submitted by an earlier run
+func (rls *RLSClient) refreshBatchResult(ctx context.Context, previous BatchResult, opts BatchOptions) BatchResult {
+	result := previous
+	result.Error = ""
+	var wd *Withdrawal
+	var err error
+	if opts.Wait {
+		wd, err = rls.WaitForWithdrawal(ctx, previous.WithdrawalID, opts.WaitOptions)
+	} else {
+		wd, err = rls.GetWithdrawalContext(ctx, previous.WithdrawalID)
+	}
+	if wd != nil {
+		result.State = wd.State
+		result.FeePaid = wd.FeePaid
+	}
+	if err != nil && !errors.Is(err, ErrWithdrawalFailed) {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// batchIdempotencyKey derives the idempotency key of a row from its reference and invoice, as a
+// version 8 (custom) UUID holding a SHA-256 hash of both
+func batchIdempotencyKey(reference, invoice string) string {
+	b := sha256.Sum256([]byte("rls-batch\x00" + reference + "\x00" + invoice))
+	b[6] = (b[6] & 0x0f) | 0x80
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// runConcurrently calls f for every index, concurrency at a time, until ctx is done or stop is closed.
+// It returns true if stop was closed before every index was started.
+func runConcurrently(ctx context.Context, stop <-chan struct{}, concurrency int, indexes []int, f func(i int)) bool {
+	if concurrency < 1 {
+		concurrency = DefaultBatchConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	stopped := false
+	for _, i := range indexes {
+		select {
+		case <-ctx.Done():
+		case <-stop:
+		case sem <- struct{}{}:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if isClosed(stop) {
+			stopped = true
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			f(i)
+		}(i)
+	}
+	wg.Wait()
+	return stopped
+}
+
+// isClosed returns true if ch is closed. A nil channel is never closed.
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// batch file columns, shared by the CSV and JSON formats
+const (
+	batchColumnReference    = "reference"
+	batchColumnInvoice      = "invoice"
+	batchColumnAmount       = "amount"
+	batchColumnFeeLimit     = "fee_limit"
+	batchColumnWithdrawalID = "withdrawal_id"
+	batchColumnState        = "state"
+	batchColumnFeePaid      = "fee_paid"
+	batchColumnError        = "error"
+)
+
+// batchResultColumns are the columns written by BatchResultWriter
+var batchResultColumns = []string{
+	batchColumnReference, batchColumnInvoice, batchColumnAmount, batchColumnFeeLimit,
+	batchColumnWithdrawalID, batchColumnState, batchColumnFeePaid, batchColumnError,
+}
+
+// ReadBatchFile reads the rows of a batch from a JSON file, if its extension is .json, or a CSV file.
+// See ReadBatchCSV and ReadBatchJSON.
+func ReadBatchFile(path string) ([]BatchRow, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch : %w", err)
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return ReadBatchJSON(f)
+	}
+	return ReadBatchCSV(f)
+}
+
+// ReadBatchCSV reads the rows of a batch from CSV with a header naming the reference, invoice, amount and
+// fee_limit columns, in any order. Only reference and invoice are required. Amounts are parsed with
+// ParseAmount and fee limits with ParseFeePolicy; empty cells leave them to the invoice and BatchOptions.
+func ReadBatchCSV(r io.Reader) ([]BatchRow, error) {
+	records, err := readCSV(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch : %w", err)
+	}
+	var rows []BatchRow
+	for i, record := range records {
+		row, err := parseBatchRow(record[batchColumnReference], record[batchColumnInvoice], record[batchColumnAmount], record[batchColumnFeeLimit])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read batch : line %d : %w", i+2, err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// ReadBatchJSON reads the rows of a batch from a JSON array of objects with the reference, invoice, amount
+// and fee_limit fields. Amounts and fee limits are either strings, parsed like ReadBatchCSV, or numbers of sats.
+func ReadBatchJSON(r io.Reader) ([]BatchRow, error) {
+	var records []struct {
+		Reference string          `json:"reference"`
+		Invoice   string          `json:"invoice"`
+		Amount    json.RawMessage `json:"amount"`
+		FeeLimit  json.RawMessage `json:"fee_limit"`
+	}
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, fmt.Errorf("failed to read batch : %w", err)
+	}
+	rows := make([]BatchRow, len(records))
+	for i, record := range records {
+		row, err := parseBatchRow(record.Reference, record.Invoice, jsonText(record.Amount), jsonText(record.FeeLimit))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read batch : row %d : %w", i+1, err)
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
+
+// jsonText returns a JSON string's value, or the text of any other JSON value
+func jsonText(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	if text := string(bytes.TrimSpace(raw)); text != "null" {
+		return text
+	}
+	return ""
+}
+
+// parseBatchRow parses the cells of a batch row
+func parseBatchRow(reference, invoice, amount, feeLimit string) (BatchRow, error) {
+	row := BatchRow{Reference: strings.TrimSpace(reference), Invoice: strings.TrimSpace(invoice)}
+	if amount = strings.TrimSpace(amount); amount != "" {
+		var err error
+		if row.Amount, err = ParseAmount(amount); err != nil {
+			return row, err
+		}
+	}
+	if feeLimit = strings.TrimSpace(feeLimit); feeLimit != "" {
+		var err error
+		if row.FeePolicy, err = ParseFeePolicy(feeLimit); err != nil {
+			return row, err
+		}
+	}
+	return row, nil
+}
+
+// ReadBatchResults reads the results written by BatchResultWriter. A missing file has no results.
+func ReadBatchResults(path string) ([]BatchResult, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch results : %w", err)
+	}
+	defer f.Close()
+
+	records, err := readCSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch results : %w", err)
+	}
+	results := make([]BatchResult, len(records))
+	for i, record := range records {
+		result := BatchResult{
+			Reference:    record[batchColumnReference],
+			Invoice:      record[batchColumnInvoice],
+			WithdrawalID: record[batchColumnWithdrawalID],
+			State:        WithdrawalState(record[batchColumnState]),
+			Error:        record[batchColumnError],
+		}
+		for column, amount := range map[string]*Amount{
+			batchColumnAmount:   &result.Amount,
+			batchColumnFeeLimit: &result.FeeLimit,
+			batchColumnFeePaid:  &result.FeePaid,
+		} {
+			if record[column] == "" {
+				continue
+			}
+			if *amount, err = ParseAmount(record[column]); err != nil {
+				return nil, fmt.Errorf("failed to read batch results : line %d : %w", i+2, err)
+			}
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+// readCSV reads CSV records as maps from the lowercased header names to the cells
+func readCSV(r io.Reader) ([]map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+
+	var records []map[string]string
+	for {
+		cells, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := make(map[string]string, len(header))
+		for i, cell := range cells {
+			if i < len(header) {
+				record[header[i]] = cell
+			}
+		}
+		records = append(records, record)
+	}
+}
+
+// BatchResultWriter appends batch results to a CSV file as they are reported, so an interrupted batch
+// can be resumed from it. It is safe for concurrent use.
+type BatchResultWriter struct {
+	mu sync.Mutex
+	f  *os.File
+	w  *csv.Writer
+}
+
+// OpenBatchResultWriter opens the results file at path for appending, writing the header if it is new
+func OpenBatchResultWriter(path string) (*BatchResultWriter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open batch results : %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to open batch results : %w", err)
+	}
+	w := &BatchResultWriter{f: f, w: csv.NewWriter(f)}
+	if info.Size() == 0 {
+		if err := w.write(batchResultColumns); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// Write appends result to the file and flushes it
+func (w *BatchResultWriter) Write(result BatchResult) error {
+	return w.write([]string{
+		result.Reference,
+		result.Invoice,
+		result.Amount.decimal(UnitSat),
+		result.FeeLimit.decimal(UnitSat),
+		result.WithdrawalID,
+		string(result.State),
+		result.FeePaid.decimal(UnitSat),
+		result.Error,
+	})
+}
+
+func (w *BatchResultWriter) write(record []string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.w.Write(record); err != nil {
+		return fmt.Errorf("failed to write batch results : %w", err)
+	}
+	w.w.Flush()
+	if err := w.w.Error(); err != nil {
+		return fmt.Errorf("failed to write batch results : %w", err)
+	}
+	return nil
+}
+
+// Close closes the file
+func (w *BatchResultWriter) Close() error {
+	return w.f.Close()
+}
@@ -0,0 +1,331 @@
+package rls
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchServer is an RLS fake paying invoices named "lnbc<amount in sats>", or "lnbc" for amountless ones
+type batchServer struct {
+	available int64
+	fee       int64
+	// submit, if set, is called before a withdrawal is accepted
+	submit func(r *http.Request)
+
+	mu        sync.Mutex
+	submitted []Withdrawal
+	keys      []string
+	polled    []string
+}
+
+func (s *batchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/lightning/parse_invoice":
+		var req map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		var amount int64
+		fmt.Sscanf(req["destination"], "lnbc%d", &amount)
+		fmt.Fprintf(w, `{"amount":%d,"destination":%q}`, amount, req["destination"])
+	case r.URL.Path == "/accounts/acct":
+		fmt.Fprintf(w, `{"id":"acct","balance":%d,"available_balance":%d}`, s.available, s.available)
+	case r.URL.Path == "/lightning/estimate_fee":
+		fmt.Fprintf(w, `{"fee":%d}`, s.fee)
+	case r.URL.Path == "/accounts/acct/withdrawals" && r.Method == http.MethodPost:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if s.submit != nil {
+			s.submit(r)
+		}
+		var wd Withdrawal
+		if err := json.Unmarshal(body, &wd); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.submitted = append(s.submitted, wd)
+		s.keys = append(s.keys, r.Header.Get(IdempotencyKeyHeader))
+		wd.ID = fmt.Sprintf("wd_%d", len(s.submitted))
+		s.mu.Unlock()
+		wd.State = WithdrawalStatePending
+		_ = json.NewEncoder(w).Encode(wd)
+	case strings.HasPrefix(r.URL.Path, "/accounts/acct/withdrawals/"):
+		id := strings.TrimPrefix(r.URL.Path, "/accounts/acct/withdrawals/")
+		s.mu.Lock()
+		s.polled = append(s.polled, id)
+		s.mu.Unlock()
+		fmt.Fprintf(w, `{"id":%q,"amount":1000,"state":"SUCCESS","fee_paid":2}`, id)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestPayBatch(t *testing.T) {
+	srv := &batchServer{available: 10000}
+	client := newTestClient(t, srv.ServeHTTP)
+	rows := []BatchRow{
+		{Reference: "a", Invoice: "lnbc1000"},
+		{Reference: "b", Invoice: "lnbc", Amount: Sats(2000), FeePolicy: AbsoluteFee(0)},
+	}
+	var reported []string
+	report, err := client.PayBatch(context.Background(), rows, BatchOptions{
+		// the budget only fits if the zero fee limit of row b is kept
+		Budget:    Sats(3010),
+		FeePolicy: AbsoluteFee(Sats(10)),
+		OnResult:  func(result BatchResult) { reported = append(reported, result.Reference) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Pending != 2 || len(reported) != 2 || len(srv.submitted) != 2 {
+		t.Fatalf("got %+v, reported %v", report, reported)
+	}
+	limits := map[Amount]Amount{}
+	for _, wd := range srv.submitted {
+		limits[wd.Amount] = wd.Details.FeeLimit
+	}
+	if limits[Sats(1000)] != Sats(10) || limits[Sats(2000)] != 0 {
+		t.Errorf("submitted fee limits %v", limits)
+	}
+	for _, result := range report.Results {
+		if result.WithdrawalID == "" || result.State != WithdrawalStatePending || result.Error != "" {
+			t.Errorf("got %+v", result)
+		}
+	}
+	keys := map[string]bool{batchIdempotencyKey("a", "lnbc1000"): true, batchIdempotencyKey("b", "lnbc"): true}
+	for _, key := range srv.keys {
+		if !keys[key] {
+			t.Errorf("unexpected idempotency key %s", key)
+		}
+	}
+}
+
+func TestValidateBatch(t *testing.T) {
+	srv := &batchServer{available: 10000}
+	client := newTestClient(t, srv.ServeHTTP)
+
+	_, err := client.ValidateBatch(context.Background(), []BatchRow{
+		{Reference: "a", Invoice: "lnbc1000"},
+		{Reference: "a", Invoice: "lnbc2000"},
+		{Reference: "c", Invoice: "lnbc"},
+		{Reference: "d", Invoice: "lnbc1000", Amount: Sats(999)},
+	}, BatchOptions{})
+	var invalid *BatchValidationError
+	if !errors.As(err, &invalid) || len(invalid.Rows) != 3 {
+		t.Fatalf("expected 3 invalid rows, got %v", err)
+	}
+	if !errors.Is(invalid.Rows[1].Err, ErrAmountRequired) || !errors.Is(invalid.Rows[2].Err, ErrAmountMismatch) {
+		t.Errorf("got %v", invalid)
+	}
+
+	rows := []BatchRow{{Reference: "a", Invoice: "lnbc1000", FeePolicy: AbsoluteFee(Sats(10))}}
+	if _, err := client.ValidateBatch(context.Background(), rows, BatchOptions{Budget: Sats(1009)}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("expected a budget error, got %v", err)
+	}
+	srv.available = 1009
+	if _, err := client.ValidateBatch(context.Background(), rows, BatchOptions{}); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("expected an insufficient funds error, got %v", err)
+	}
+	if _, err := client.PayBatch(context.Background(), rows, BatchOptions{}); err == nil || len(srv.submitted) != 0 {
+		t.Errorf("invalid batch paid: %v", err)
+	}
+}
+
+func TestPayBatchResume(t *testing.T) {
+	srv := &batchServer{available: 10000}
+	client := newTestClient(t, srv.ServeHTTP)
+	rows := []BatchRow{
+		{Reference: "done", Invoice: "lnbc1000"},
+		{Reference: "pending", Invoice: "lnbc1000"},
+		{Reference: "unsubmitted", Invoice: "lnbc1000"},
+	}
+	previous := []BatchResult{
+		{Reference: "done", Invoice: "lnbc1000", Amount: Sats(1000), WithdrawalID: "wd_done", State: WithdrawalStateSuccess},
+		{Reference: "pending", Invoice: "lnbc1000", Amount: Sats(1000), WithdrawalID: "wd_pending", State: WithdrawalStatePending},
+		{Reference: "unsubmitted", Invoice: "lnbc1000", Error: "timeout"},
+	}
+	report, err := client.PayBatch(context.Background(), rows, BatchOptions{Previous: previous})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(srv.submitted) != 1 || srv.keys[0] != batchIdempotencyKey("unsubmitted", "lnbc1000") {
+		t.Errorf("got submissions %v", srv.keys)
+	}
+	if len(srv.polled) != 1 || srv.polled[0] != "wd_pending" {
+		t.Errorf("got polls %v", srv.polled)
+	}
+	if report.Succeeded != 2 || report.Pending != 1 || report.Paid != Sats(2000) || report.Fees != Sats(2) {
+		t.Errorf("got %+v", report)
+	}
+
+	// a reference submitted for another invoice is not paid again
+	rows[1].Invoice = "lnbc2000"
+	if _, err := client.PayBatch(context.Background(), rows, BatchOptions{Previous: previous}); err == nil {
+		t.Error("expected an error for an invoice differing from the previous run")
+	}
+}
+
+func TestPayBatchCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := &batchServer{available: 10000, submit: func(r *http.Request) {
+		// the batch is interrupted while the first row is in flight
+		cancel()
+		<-r.Context().Done()
+	}}
+	client := newTestClient(t, srv.ServeHTTP)
+	rows := []BatchRow{
+		{Reference: "a", Invoice: "lnbc1000"},
+		{Reference: "b", Invoice: "lnbc1000"},
+	}
+	var reported []BatchResult
+	report, err := client.PayBatch(ctx, rows, BatchOptions{
+		Concurrency: 1,
+		OnResult:    func(result BatchResult) { reported = append(reported, result) },
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a canceled error, got %v", err)
+	}
+	if len(reported) != 1 || reported[0].Reference != "a" || reported[0].Error == "" {
+		t.Errorf("in-flight row not reported: %+v", reported)
+	}
+	if report.Errors != 1 || report.NotStarted != 1 {
+		t.Errorf("got %+v", report)
+	}
+}
+
+func TestPayBatchStopped(t *testing.T) {
+	stop := make(chan struct{})
+	var once sync.Once
+	var canceled error
+	srv := &batchServer{available: 10000, submit: func(r *http.Request) {
+		// the batch is interrupted while the first row is in flight, which must not cancel its request
+		once.Do(func() { close(stop) })
+		time.Sleep(20 * time.Millisecond)
+		canceled = r.Context().Err()
+	}}
+	client := newTestClient(t, srv.ServeHTTP)
+	rows := []BatchRow{
+		{Reference: "a", Invoice: "lnbc1000"},
+		{Reference: "b", Invoice: "lnbc1000"},
+	}
+	var reported []BatchResult
+	report, err := client.PayBatch(context.Background(), rows, BatchOptions{
+		Concurrency: 1,
+		Stop:        stop,
+		OnResult:    func(result BatchResult) { reported = append(reported, result) },
+	})
+	if !errors.Is(err, ErrBatchStopped) {
+		t.Errorf("expected a stopped error, got %v", err)
+	}
+	if canceled != nil {
+		t.Errorf("in-flight request canceled: %v", canceled)
+	}
+	if len(reported) != 1 || reported[0].Reference != "a" || reported[0].WithdrawalID == "" || reported[0].Error != "" {
+		t.Errorf("in-flight row not completed: %+v", reported)
+	}
+	if len(srv.submitted) != 1 || report.Pending != 1 || report.NotStarted != 1 {
+		t.Errorf("got %+v after %d submissions", report, len(srv.submitted))
+	}
+
+	// stopping during validation pays nothing
+	srv = &batchServer{available: 10000}
+	client = newTestClient(t, srv.ServeHTTP)
+	if _, err := client.PayBatch(context.Background(), rows, BatchOptions{Stop: stop}); !errors.Is(err, ErrBatchStopped) || len(srv.submitted) != 0 {
+		t.Errorf("got %v after %d submissions", err, len(srv.submitted))
+	}
+}
+
+func TestBatchIdempotencyKey(t *testing.T) {
+	key := batchIdempotencyKey("ref", "lnbc1")
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-8[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(key) {
+		t.Errorf("%s is not a version 8 UUID", key)
+	}
+	if key != batchIdempotencyKey("ref", "lnbc1") {
+		t.Error("key is not deterministic")
+	}
+	for _, other := range []string{batchIdempotencyKey("ref", "lnbc2"), batchIdempotencyKey("re", "flnbc1")} {
+		if other == key {
+			t.Errorf("distinct rows share the key %s", key)
+		}
+	}
+}
+
+func TestReadBatch(t *testing.T) {
+	want := []BatchRow{
+		{Reference: "a", Invoice: "lnbc1"},
+		{Reference: "b", Invoice: "lnbc2", Amount: Sats(2100), FeePolicy: PercentFee(0.5)},
+		{Reference: "c", Invoice: "lnbc3", Amount: Sats(10), FeePolicy: AbsoluteFee(0)},
+	}
+	csvRows, err := ReadBatchCSV(strings.NewReader("Invoice,reference,amount,fee_limit\nlnbc1,a,,\nlnbc2, b,2.1k,0.5%\nlnbc3,c,10,0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonRows, err := ReadBatchJSON(strings.NewReader(`[
+		{"reference":"a","invoice":"lnbc1","amount":null},
+		{"reference":"b","invoice":"lnbc2","amount":"2.1k","fee_limit":"0.5%"},
+		{"reference":"c","invoice":"lnbc3","amount":10,"fee_limit":0}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rows := range [][]BatchRow{csvRows, jsonRows} {
+		if len(rows) != len(want) {
+			t.Fatalf("got %+v", rows)
+		}
+		for i := range want {
+			if rows[i] != want[i] {
+				t.Errorf("row %d: got %+v, want %+v", i, rows[i], want[i])
+			}
+		}
+	}
+
+	if _, err := ReadBatchCSV(strings.NewReader("reference,invoice,fee_limit\na,lnbc1,200%\n")); err == nil {
+		t.Error("expected an error for an invalid fee limit")
+	}
+}
+
+func TestBatchResults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.csv")
+	if results, err := ReadBatchResults(path); err != nil || results != nil {
+		t.Fatalf("missing file: %v, %v", results, err)
+	}
+	want := []BatchResult{
+		{Reference: "a", Invoice: "lnbc1", Amount: Sats(1000), FeeLimit: 0, WithdrawalID: "wd_1", State: WithdrawalStateSuccess, FeePaid: Sats(2)},
+		{Reference: "b, quoted", Invoice: "lnbc2", Amount: Sats(2000), FeeLimit: Sats(10), Error: "failed to pay : \"timeout\""},
+	}
+	for _, result := range want {
+		w, err := OpenBatchResultWriter(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(result); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+	}
+	got, err := ReadBatchResults(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %+v, want %+v", got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package rls
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sync"
+)
+
+// MatchMode controls how a Replayer matches requests to recorded interactions
+type MatchMode int
+
+const (
+	// MatchStrict replays interactions in recorded order and requires the operation, method,
+	// path, query and body of each request to match
+	MatchStrict MatchMode = iota
+	// MatchLoose replays the first unused interaction with the same operation, method and path,
+	// in any order
+	MatchLoose
+)
+
+// accountPathPattern matches the account ID in request paths, which is scrubbed from cassettes
+var accountPathPattern = regexp.MustCompile(`^/accounts/[^/]+`)
+
+const scrubbedAccountPath = "/accounts/{account_id}"
+
+// Cassette is a list of recorded RLS API interactions
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a single recorded request/response pair
+type Interaction struct {
+	Operation string              `json:"operation"`
+	Request   InteractionRequest  `json:"request"`
+	Response  InteractionResponse `json:"response"`
+	// Error is set instead of Response when the request failed without a response
+	Error string `json:"error,omitempty"`
+}
+
+// InteractionRequest is the recorded part of a request. Headers are not recorded, so credentials never reach the cassette.
+type InteractionRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+	Body   string `json:"body,omitempty"`
+}
+
+// InteractionResponse is the recorded part of a response
+type InteractionResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// newInteractionRequest records req with its account ID and secrets scrubbed
+func newInteractionRequest(req *http.Request) InteractionRequest {
+	return InteractionRequest{
+		Method: req.Method,
+		Path:   accountPathPattern.ReplaceAllString(req.URL.Path, scrubbedAccountPath),
+		Query:  req.URL.RawQuery,
+		Body:   LogOptions{}.redactBody(readRequestBody(req)),
+	}
+}
+
+// LoadCassette reads a cassette from the JSON file at path
+func LoadCassette(path string) (*Cassette, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette : %w", err)
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(b, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s : %w", path, err)
+	}
+	return &cassette, nil
+}
+
+// Save writes the cassette to the JSON file at path
+func (c *Cassette) Save(path string) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write cassette : %w", err)
+	}
+	return nil
+}
+
+// Recorder records every attempt of every RLS API call made through its Middleware
+type Recorder struct {
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder returns an empty Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Middleware returns a Middleware recording interactions. It should be the innermost middleware.
+func (r *Recorder) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			interaction := Interaction{
+				Operation: Operation(req.Context()),
+				Request:   newInteractionRequest(req),
+			}
+			res, err := next.RoundTrip(req)
+			if err != nil {
+				interaction.Error = err.Error()
+			} else {
+				var body []byte
+				body, res.Body = readResponseBody(res)
+				header := res.Header.Clone()
+				header.Del("Set-Cookie")
+				interaction.Response = InteractionResponse{
+					StatusCode: res.StatusCode,
+					Header:     header,
+					Body:       LogOptions{}.redactBody(string(body)),
+				}
+			}
+
+			r.mu.Lock()
+			r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+			r.mu.Unlock()
+			return res, err
+		})
+	}
+}
+
+// Cassette returns a copy of the interactions recorded so far
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Cassette{Interactions: append([]Interaction(nil), r.cassette.Interactions...)}
+}
+
+// Save writes the interactions recorded so far to the JSON file at path
+func (r *Recorder) Save(path string) error {
+	return r.Cassette().Save(path)
+}
+
+// ErrCassetteMismatch is returned by a Replayer when no recorded interaction matches a request
+var ErrCassetteMismatch = errors.New("rls: no matching interaction in cassette")
+
+// Replayer serves recorded interactions instead of sending requests
+type Replayer struct {
+	mu       sync.Mutex
+	cassette *Cassette
+	mode     MatchMode
+	used     []bool
+	next     int
+}
+
+// NewReplayer returns a Replayer serving the interactions of cassette
+func NewReplayer(cassette *Cassette, mode MatchMode) *Replayer {
+	return &Replayer{
+		cassette: cassette,
+		mode:     mode,
+		used:     make([]bool, len(cassette.Interactions)),
+	}
+}
+
+// Middleware returns a Middleware answering requests from the cassette without calling the next RoundTripper
+func (r *Replayer) Middleware() Middleware {
+	return func(http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(r.replay)
+	}
+}
+
+// Remaining returns the number of interactions that have not been replayed
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	remaining := 0
+	for _, used := range r.used {
+		if !used {
+			remaining++
+		}
+	}
+	return remaining
+}
+
+func (r *Replayer) replay(req *http.Request) (*http.Response, error) {
+	op := Operation(req.Context())
+	recorded := newInteractionRequest(req)
+
+	r.mu.Lock()
+	i := r.match(op, recorded)
+	if i < 0 {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("%w : %s %s %s", ErrCassetteMismatch, op, recorded.Method, recorded.Path)
+	}
+	r.used[i] = true
+	r.next = i + 1
+	interaction := r.cassette.Interactions[i]
+	r.mu.Unlock()
+
+	if interaction.Error != "" {
+		return nil, errors.New(interaction.Error)
+	}
+	header := interaction.Response.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+		StatusCode:    interaction.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewBufferString(interaction.Response.Body)),
+		ContentLength: int64(len(interaction.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// match returns the index of the interaction to replay for the request, or -1. r.mu must be held.
+func (r *Replayer) match(op string, req InteractionRequest) int {
+	if r.mode == MatchStrict {
+		if r.next >= len(r.cassette.Interactions) {
+			return -1
+		}
+		interaction := r.cassette.Interactions[r.next]
+		if interaction.Operation != op || interaction.Request != req {
+			return -1
+		}
+		return r.next
+	}
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] {
+			continue
+		}
+		if interaction.Operation == op &&
+			interaction.Request.Method == req.Method &&
+			interaction.Request.Path == req.Path {
+			return i
+		}
+	}
+	return -1
+}
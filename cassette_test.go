@@ -0,0 +1,85 @@
+package rls
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`{"id":"wd_1","state":"PENDING"}`))
+		default:
+			w.Write([]byte(`{"id":"wh_1","secret":"topsecret"}`))
+		}
+	})
+	recorder := NewRecorder()
+	client.Use(recorder.Middleware())
+	ctx := context.Background()
+	if _, err := client.GetWithdrawalContext(ctx, "wd_1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.SubscribeToWebhookContext(ctx, "https://example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := recorder.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cassette.Interactions) != 2 {
+		t.Fatalf("expected 2 interactions, got %d", len(cassette.Interactions))
+	}
+	if got := cassette.Interactions[0].Request.Path; got != "/accounts/{account_id}/withdrawals/wd_1" {
+		t.Errorf("account ID not scrubbed from %q", got)
+	}
+	if strings.Contains(cassette.Interactions[1].Response.Body, "topsecret") {
+		t.Errorf("secret recorded in %q", cassette.Interactions[1].Response.Body)
+	}
+
+	replayed := NewRLSClient(ctx, *NewConfig("http://unused", "key", "other", "", nil), nil)
+	replayed.RetryPolicy = nil
+	replayer := NewReplayer(cassette, MatchStrict)
+	replayed.Use(replayer.Middleware())
+	wd, err := replayed.GetWithdrawalContext(ctx, "wd_1")
+	if err != nil || wd.ID != "wd_1" {
+		t.Fatalf("got %v, %v", wd, err)
+	}
+	if replayer.Remaining() != 1 {
+		t.Errorf("expected 1 remaining interaction, got %d", replayer.Remaining())
+	}
+}
+
+func TestReplayMatchModes(t *testing.T) {
+	cassette := &Cassette{Interactions: []Interaction{
+		{Operation: OpGetWithdrawal, Request: InteractionRequest{Method: http.MethodGet, Path: "/accounts/{account_id}/withdrawals/wd_1"}, Response: InteractionResponse{StatusCode: 200, Body: `{"id":"wd_1"}`}},
+		{Operation: OpGetAccount, Request: InteractionRequest{Method: http.MethodGet, Path: "/accounts/{account_id}"}, Response: InteractionResponse{StatusCode: 200, Body: `{"id":"acct"}`}},
+	}}
+	ctx := context.Background()
+	newClient := func(mode MatchMode) *RLSClient {
+		client := NewRLSClient(ctx, *NewConfig("http://unused", "key", "acct", "", nil), nil)
+		client.RetryPolicy = nil
+		client.Use(NewReplayer(cassette, mode).Middleware())
+		return client
+	}
+
+	if _, err := newClient(MatchStrict).GetAccountContext(ctx); !errors.Is(err, ErrCassetteMismatch) {
+		t.Errorf("strict: expected a mismatch out of order, got %v", err)
+	}
+	loose := newClient(MatchLoose)
+	if _, err := loose.GetAccountContext(ctx); err != nil {
+		t.Errorf("loose: %v", err)
+	}
+	if _, err := loose.GetAccountContext(ctx); !errors.Is(err, ErrCassetteMismatch) {
+		t.Errorf("loose: expected a mismatch once the interaction is used, got %v", err)
+	}
+}
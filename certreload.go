@@ -0,0 +1,150 @@
+package rls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultCertReloadInterval is how often CertReloader.Watch checks the certificate files when no interval is given
+const DefaultCertReloadInterval = time.Minute
+
+// CertReloader holds a client certificate and reloads it when its files change, so certificates can be
+// rotated without restarting. New TLS handshakes use the current certificate through GetClientCertificate,
+// while connections already established keep the certificate they were opened with.
+// A CertReloader is safe for concurrent use.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	// OnReload, if set, is called after every reload attempt with the new leaf certificate, or the error
+	// that left the previous certificate in place
+	OnReload func(leaf *x509.Certificate, err error)
+
+	mu    sync.RWMutex
+	cert  *tls.Certificate
+	leaf  *x509.Certificate
+	stamp [2]fileStamp
+}
+
+// fileStamp identifies a version of a file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewCertReloader loads the PEM certificate and key at certFile and keyFile
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetClientCertificate returns the current certificate. It is meant for tls.Config.GetClientCertificate.
+func (r *CertReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Leaf returns the current certificate
+func (r *CertReloader) Leaf() *x509.Certificate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.leaf
+}
+
+// Expiry returns the time the current certificate expires
+func (r *CertReloader) Expiry() time.Time {
+	return r.Leaf().NotAfter
+}
+
+// ExpiresWithin returns true if the current certificate expires within d
+func (r *CertReloader) ExpiresWithin(d time.Duration) bool {
+	return time.Until(r.Expiry()) < d
+}
+
+// Reload loads the certificate files again. On error, the previous certificate stays in use.
+func (r *CertReloader) Reload() error {
+	err := r.load()
+	if r.OnReload != nil {
+		if err != nil {
+			r.OnReload(nil, err)
+		} else {
+			r.OnReload(r.Leaf(), nil)
+		}
+	}
+	return err
+}
+
+// Watch checks the certificate files every interval until ctx is done, and reloads them when either
+// changes. A certificate and key that do not match yet, e.g. halfway through a rotation, are retried
+// at the next check. It blocks, so it is usually run in its own goroutine.
+func (r *CertReloader) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultCertReloadInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if r.changed() {
+				_ = r.Reload()
+			}
+		}
+	}
+}
+
+// changed returns true if either file differs from the loaded version
+func (r *CertReloader) changed() bool {
+	stamp, err := r.stamps()
+	if err != nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return stamp != r.stamp
+}
+
+// stamps returns the current versions of the certificate and key files
+func (r *CertReloader) stamps() ([2]fileStamp, error) {
+	var stamp [2]fileStamp
+	for i, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return stamp, err
+		}
+		stamp[i] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamp, nil
+}
+
+// load reads the certificate and key and swaps them in if they are valid
+func (r *CertReloader) load() error {
+	stamp, err := r.stamps()
+	if err != nil {
+		return fmt.Errorf("failed to load client certificate : %w", err)
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load client certificate %s : %w", r.certFile, err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse client certificate %s : %w", r.certFile, err)
+	}
+	cert.Leaf = leaf
+
+	r.mu.Lock()
+	r.cert, r.leaf, r.stamp = &cert, leaf, stamp
+	r.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,153 @@
+package rls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testKeyPair returns the PEM certificate and key of a fresh self-signed certificate named name
+func testKeyPair(t *testing.T, name string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeKeyPair writes certPEM and keyPEM to path's certificate and key files, with a modification time
+// of modTime so changes are seen regardless of the file system's timestamp resolution
+func writeKeyPair(t *testing.T, path string, certPEM, keyPEM []byte, modTime time.Time) {
+	t.Helper()
+	certFile, keyFile := TLSPathFiles(path)
+	for file, data := range map[string][]byte{certFile: certPEM, keyFile: keyPEM} {
+		if err := os.WriteFile(file, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client")
+	certA, keyA := testKeyPair(t, "a")
+	certB, keyB := testKeyPair(t, "b")
+	now := time.Now()
+	writeKeyPair(t, path, certA, keyA, now.Add(-time.Minute))
+
+	reloader, err := NewCertReloader(TLSPathFiles(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloader.Leaf().Subject.CommonName != "a" || reloader.changed() {
+		t.Fatalf("got %s", reloader.Leaf().Subject.CommonName)
+	}
+	if !reloader.ExpiresWithin(25*time.Hour) || reloader.ExpiresWithin(time.Hour) {
+		t.Errorf("unexpected expiry %s", reloader.Expiry())
+	}
+
+	var reloads, failures int
+	reloader.OnReload = func(leaf *x509.Certificate, err error) {
+		if err != nil {
+			failures++
+		} else {
+			reloads++
+		}
+	}
+
+	// a certificate rotated before its key does not match and keeps the previous one in use
+	writeKeyPair(t, path, certB, keyA, now)
+	if err := reloader.Reload(); err == nil {
+		t.Error("expected an error loading a mismatched certificate and key")
+	}
+	if reloader.Leaf().Subject.CommonName != "a" {
+		t.Errorf("previous certificate replaced by %s", reloader.Leaf().Subject.CommonName)
+	}
+	if !reloader.changed() {
+		t.Error("a failed reload should be retried")
+	}
+
+	writeKeyPair(t, path, certB, keyB, now)
+	if err := reloader.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := reloader.GetClientCertificate(nil)
+	if err != nil || cert.Leaf.Subject.CommonName != "b" {
+		t.Errorf("got %v, %v", cert, err)
+	}
+	if reloads != 1 || failures != 1 || reloader.changed() {
+		t.Errorf("got %d reloads and %d failures", reloads, failures)
+	}
+
+	if _, err := NewCertReloader(TLSPathFiles(path + ".missing")); err == nil {
+		t.Error("expected an error for missing files")
+	}
+}
+
+func TestCertReloaderWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client")
+	certA, keyA := testKeyPair(t, "a")
+	certB, keyB := testKeyPair(t, "b")
+	writeKeyPair(t, path, certA, keyA, time.Now().Add(-time.Minute))
+	reloader, err := NewCertReloader(TLSPathFiles(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reloads int32
+	reloader.OnReload = func(*x509.Certificate, error) { atomic.AddInt32(&reloads, 1) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		reloader.Watch(ctx, 5*time.Millisecond)
+		close(done)
+	}()
+
+	writeKeyPair(t, path, certB, keyB, time.Now())
+	deadline := time.Now().Add(2 * time.Second)
+	for reloader.Leaf().Subject.CommonName != "b" {
+		if time.Now().After(deadline) {
+			t.Fatal("rotated certificate not picked up")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&reloads); n != 1 {
+		t.Errorf("got %d reloads of unchanged files", n)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Watch did not return after the context was canceled")
+	}
+}
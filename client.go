@@ -18,35 +18,96 @@ const (
 
 // Client is the interface of a RLS API Client
 type Client interface {
+	// PingContext does ping pong with the API server at /
+	PingContext(ctx context.Context) bool
+	// GetAccountContext returns the account's balances
+	GetAccountContext(ctx context.Context) (*Account, error)
+	// NewWithdrawalContext initiates a withdrawal from RLS API by paying a specific invoice
+	NewWithdrawalContext(ctx context.Context, withdrawal *Withdrawal) (*Withdrawal, error)
+	// GetWithdrawalContext returns a withdrawal based on the passed withdrawal_id
+	GetWithdrawalContext(ctx context.Context, withdrawalID string) (*Withdrawal, error)
+	// ListWithdrawalsContext returns a list of recent withdrawals
+	ListWithdrawalsContext(ctx context.Context, limit int64, nextTimestamp int64) (*WithdrawalList, error)
+	// NewInvoiceContext creates an invoice to enable deposits to RLS
+	NewInvoiceContext(ctx context.Context, amount Amount, label string, network string) (*Invoice, error)
+	// GetInvoiceContext gets an existing deposit intent from RLS using its ID
+	GetInvoiceContext(ctx context.Context, invoiceID string) (*Invoice, error)
+	// GetInvoicesContext queries a list of invoices generated by RLS
+	GetInvoicesContext(ctx context.Context, limit int64, nextTimestamp int64) (*InvoiceList, error)
+	// GetDepositContext returns a single deposit based on its depositID
+	GetDepositContext(ctx context.Context, depositID string) (*Deposit, error)
+	// GetDepositsContext returns a list of deposits (settled invoices) to RLS
+	GetDepositsContext(ctx context.Context, limit int64, nextTimestamp int64) (*DepositList, error)
+	// SubscribeToWebhookContext subscribes to a webhook
+	SubscribeToWebhookContext(ctx context.Context, callbackURL string) (*Webhook, error)
+	// GetSubscribedWebhookContext queries subscribed webhook
+	GetSubscribedWebhookContext(ctx context.Context) (*Webhook, error)
+	// DeleteWebhookContext deletes the existing webhook
+	DeleteWebhookContext(ctx context.Context, callbackURL string) error
+	// DecodeInvoiceContext decodes a Lightning Invoice using RLS using `lncli decodepayreq`
+	DecodeInvoiceContext(ctx context.Context, invoice string) (*DecodedInvoice, error)
+	// EstimateLightningFeeContext estimates Lightning Fee of an invoice using `lncli`
+	EstimateLightningFeeContext(ctx context.Context, invoice string, amount Amount) (*FeeEstimate, error)
+
 	// Ping does ping pong with the API server at /
+	//
+	// Deprecated: use PingContext
 	Ping() bool
 	// GetAccount returns the account's balances
+	//
+	// Deprecated: use GetAccountContext
 	GetAccount() (*Account, error)
 	// NewWithdrawal initiates a withdrawal from RLS API by paying a specific invoice
+	//
+	// Deprecated: use NewWithdrawalContext
 	NewWithdrawal(withdrawal *Withdrawal) (*Withdrawal, error)
 	// GetWithdrawal returns a withdrawal based on the passed withdrawal_id
+	//
+	// Deprecated: use GetWithdrawalContext
 	GetWithdrawal(withdrawalID string) (*Withdrawal, error)
 	// ListWithdrawals returns a list of recent withdrawals
+	//
+	// Deprecated: use ListWithdrawalsContext
 	ListWithdrawals(limit int64, nextTimestamp int64) (*WithdrawalList, error)
 	// NewInvoice creates an invoice to enable deposits to RLS
+	//
+	// Deprecated: use NewInvoiceContext
 	NewInvoice(amount int64, label string, network string) (*Invoice, error)
 	// GetInvoice gets an existing deposit intent from RLS using its ID
+	//
+	// Deprecated: use GetInvoiceContext
 	GetInvoice(invoiceID string) (*Invoice, error)
 	// GetInvoices queries a list of invoices generated by RLS
+	//
+	// Deprecated: use GetInvoicesContext
 	GetInvoices(limit int64, nextTimestamp int64) (*InvoiceList, error)
 	// GetDeposit returns a single deposit based on its depositID
+	//
+	// Deprecated: use GetDepositContext
 	GetDeposit(depositID string) (*Deposit, error)
 	// GetDeposits returns a list of deposits (settled invoices) to RLS
+	//
+	// Deprecated: use GetDepositsContext
 	GetDeposits(limit int64, nextTimestamp int64) (*DepositList, error)
 	// SubscribeToWebhook subscribes to a webhook
+	//
+	// Deprecated: use SubscribeToWebhookContext
 	SubscribeToWebhook(callbackURL string) (*Webhook, error)
 	// GetSubscribedWebhook queries subscribed webhook
+	//
+	// Deprecated: use GetSubscribedWebhookContext
 	GetSubscribedWebhook() (*Webhook, error)
 	// DeleteWebhook deletes the existing webhook
+	//
+	// Deprecated: use DeleteWebhookContext
 	DeleteWebhook(callbackURL string) error
 	// DecodeInvoice decodes a Lightning Invoice using RLS using `lncli decodepayreq`
+	//
+	// Deprecated: use DecodeInvoiceContext
 	DecodeInvoice(invoice string) (*DecodedInvoice, error)
 	// EstimateLightningFee estimates Lightning Fee of an invoice using `lncli`
+	//
+	// Deprecated: use EstimateLightningFeeContext
 	EstimateLightningFee(invoice string, amount int64) (*FeeEstimate, error)
 }
 
@@ -56,9 +117,20 @@ var _ Client = &RLSClient{}
 // RLSClient is the client for the RLS API
 // RLSClient implements Client
 type RLSClient struct {
+	// Ctx is the context used by the deprecated methods that do not take a context
 	Ctx        context.Context
 	cfg        Config
 	HTTPClient *http.Client
+	// RetryPolicy controls retries of failed requests. A nil RetryPolicy disables retries
+	RetryPolicy *RetryPolicy
+	// TimestampUnit is the unit of the time-based cursors sent to RLS. TimestampAuto detects it
+	// from the timestamps RLS returns
+	TimestampUnit TimestampUnit
+	userAgent     string
+	timeouts      operationTimeouts
+	middleware    []Middleware
+	// observedUnit is the TimestampUnit of the last timestamp returned by a list call
+	observedUnit int32
 }
 
 // BaseURL returns the base url used by this RLS client
@@ -75,22 +147,39 @@ func (rls *RLSClient) Credential() string {
 	return rls.cfg.credential
 }
 
-// NewRLSClient creates a new RLSClient
+// NewRLSClient creates a new RLSClient using DefaultRetryPolicy.
+// New is preferred, as it also configures timeouts and a tuned transport.
 func NewRLSClient(ctx context.Context, cfg Config, httpClient *http.Client) *RLSClient {
 	return &RLSClient{
-		Ctx:        ctx,
-		cfg:        cfg,
-		HTTPClient: httpClient,
+		Ctx:         ctx,
+		cfg:         cfg,
+		HTTPClient:  httpClient,
+		RetryPolicy: DefaultRetryPolicy(),
+	}
+}
+
+// context returns the client's default context, used by the deprecated non-context methods
+func (rls *RLSClient) context() context.Context {
+	if rls.Ctx == nil {
+		return context.Background()
 	}
+	return rls.Ctx
 }
 
 // Ping does ping pong with the API server at /
+//
+// Deprecated: use PingContext
 func (rls *RLSClient) Ping() bool {
+	return rls.PingContext(rls.context())
+}
+
+// PingContext does ping pong with the API server at /
+func (rls *RLSClient) PingContext(ctx context.Context) bool {
 	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/", rls.BaseURL()), nil)
 	if err != nil {
 		return false
 	}
 	// empty body response
-	err = rls.sendRequest(req, nil)
+	err = rls.sendRequest(ctx, OpPing, req, nil)
 	return err == nil
 }
@@ -0,0 +1,43 @@
+package rls
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestContextCancelsRequest(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetAccountContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestDeprecatedMethodsUseClientContext(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"wd_1"}`))
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	client.Ctx = ctx
+	if wd, err := client.GetWithdrawal("wd_1"); err != nil || wd.ID != "wd_1" {
+		t.Fatalf("got %v, %v", wd, err)
+	}
+	cancel()
+	if _, err := client.GetWithdrawal("wd_1"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled, got %v", err)
+	}
+}
+
+func TestNilClientContext(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {})
+	client.Ctx = nil
+	if !client.Ping() {
+		t.Fatal("expected Ping to succeed with a nil Ctx")
+	}
+}
@@ -3,7 +3,10 @@ package main
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 
+	"github.com/SachinMeier/rls-client"
 	cli "github.com/urfave/cli"
 )
 
@@ -23,10 +26,24 @@ func cliGetAccount(ctx *cli.Context) {
 		return
 	}
 
-	acct, err := client.GetAccount()
+	acct, err := client.GetAccountContext(client.Ctx)
 	if err != nil {
 		fmt.Printf("Error GetAccount: %s\n", err.Error())
 		return
 	}
 	printAccount(acct)
 }
+
+// isBTC returns true if currency is BTC, the only currency whose amounts accept units
+func isBTC(currency string) bool {
+	return strings.EqualFold(currency, rls.CurrencyBTC)
+}
+
+// parseBaseUnits parses an amount of a currency other than BTC, a whole number in the currency's base unit
+func parseBaseUnits(s string) (int64, error) {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a whole number of the currency's base unit", s)
+	}
+	return n, nil
+}
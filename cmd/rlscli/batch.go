@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/SachinMeier/rls-client"
+	cli "github.com/urfave/cli"
+)
+
+const (
+	flagResults     = "results"
+	flagConcurrency = "concurrency"
+	flagBudget      = "budget"
+	flagDryRun      = "dry_run"
+)
+
+var batchPay = cli.Command{
+	Name:      "batchpay",
+	Category:  "Withdrawals",
+	Usage:     "Pays the invoices listed in a CSV or JSON file",
+	ArgsUsage: "file",
+	Flags: append([]cli.Flag{
+		cli.StringFlag{
+			Name:  flagResults,
+			Usage: "Results file, appended to as rows complete (defaults to <file>.results.csv)",
+		},
+		cli.IntFlag{
+			Name:  flagConcurrency,
+			Usage: "Number of rows paid at once",
+			Value: rls.DefaultBatchConcurrency,
+		},
+		cli.StringFlag{
+			Name:  flagBudget,
+			Usage: "Maximum total of the amounts and fee limits of the batch, with the same units as --amt (defaults to no limit)",
+		},
+		cli.BoolFlag{
+			Name:  flagDryRun,
+			Usage: "Validates the batch without paying",
+		},
+		cli.BoolFlag{
+			Name:  flagWait,
+			Usage: "Waits until every withdrawal succeeds or fails, so results hold the fee paid",
+		},
+		cli.DurationFlag{
+			Name:  flagTimeout,
+			Usage: "With --wait, gives up waiting for a withdrawal after this long, e.g. 5m (defaults to no limit)",
+		},
+	}, feePolicyFlags...),
+	Description: `
+	Reads rows with the reference, invoice, amount and fee_limit columns from a CSV file with a header,
+	or fields from a JSON array of objects. Only reference and invoice are required; the fee policy
+	flags apply to rows without a fee_limit.
+
+	Every row is validated before anything is paid. Results are appended to the results file as rows
+	complete, with the withdrawal ID, state, fee paid and error of each row. Running the same batch
+	again resumes it from the results file: rows already submitted are not paid again. Interrupting
+	the batch stops starting rows, and the rows in flight complete and are recorded before exiting.
+	`,
+	Action: cliBatchPay,
+}
+
+func cliBatchPay(ctx *cli.Context) {
+	path := ctx.Args().First()
+	if path == "" {
+		fmt.Printf("file must be passed as first argument\n")
+		return
+	}
+	rows, err := rls.ReadBatchFile(path)
+	if err != nil {
+		fmt.Printf("Error BatchPay: %s\n", err.Error())
+		return
+	}
+
+	resultsPath := ctx.String(flagResults)
+	if resultsPath == "" {
+		resultsPath = path + ".results.csv"
+	}
+	previous, err := rls.ReadBatchResults(resultsPath)
+	if err != nil {
+		fmt.Printf("Error BatchPay: %s\n", err.Error())
+		return
+	}
+
+	opts := rls.BatchOptions{
+		Concurrency: ctx.Int(flagConcurrency),
+		Previous:    previous,
+		Wait:        ctx.Bool(flagWait),
+		WaitOptions: rls.WaitOptions{Timeout: ctx.Duration(flagTimeout)},
+	}
+	if ctx.IsSet(flagBudget) {
+		opts.Budget, err = rls.ParseAmount(ctx.String(flagBudget))
+		if err != nil {
+			fmt.Printf("invalid budget: %s\n", err.Error())
+			return
+		}
+	}
+	opts.FeePolicy, err = feePolicy(ctx)
+	if err != nil {
+		fmt.Printf("%s\n", err.Error())
+		return
+	}
+
+	// an interrupt only stops starting rows: requests run on an uncancelled context, so the rows in
+	// flight complete and are recorded in the results file. A second interrupt exits immediately.
+	interruptCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-interruptCtx.Done()
+		stop()
+	}()
+	opts.Stop = interruptCtx.Done()
+	client, err := NewRLSClient(context.Background(), ctx)
+	if err != nil {
+		errFailedToCreateRLSClient(err)
+		return
+	}
+
+	if ctx.Bool(flagDryRun) {
+		plan, err := client.ValidateBatch(client.Ctx, rows, opts)
+		if err != nil {
+			printBatchError(err)
+			return
+		}
+		printBatchPlan(plan)
+		return
+	}
+
+	if len(previous) > 0 {
+		fmt.Fprintf(os.Stderr, "resuming from %s\n", resultsPath)
+	}
+	results, err := rls.OpenBatchResultWriter(resultsPath)
+	if err != nil {
+		fmt.Printf("Error BatchPay: %s\n", err.Error())
+		return
+	}
+	defer results.Close()
+	opts.OnResult = func(result rls.BatchResult) {
+		if err := results.Write(result); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		}
+		printBatchResult(result)
+	}
+
+	report, err := client.PayBatch(client.Ctx, rows, opts)
+	if err != nil {
+		printBatchError(err)
+	}
+	if report.Succeeded+report.Failed+report.Pending+report.Errors > 0 {
+		printBatchReport(report, resultsPath)
+	}
+}
+
+// printBatchError prints a batch error, with one line per invalid row
+func printBatchError(err error) {
+	var invalid *rls.BatchValidationError
+	if !errors.As(err, &invalid) {
+		fmt.Printf("Error BatchPay: %s\n", err.Error())
+		return
+	}
+	fmt.Printf("Error BatchPay: %d invalid rows, nothing was paid\n", len(invalid.Rows))
+	for _, row := range invalid.Rows {
+		fmt.Printf("  %s\n", row.Error())
+	}
+}
+
+// printBatchResult prints the outcome of a row on stderr
+func printBatchResult(result rls.BatchResult) {
+	var outcome []string
+	if result.WithdrawalID != "" {
+		outcome = append(outcome, result.WithdrawalID, string(result.State))
+	}
+	if result.State.IsSuccess() {
+		outcome = append(outcome, "fee "+result.FeePaid.String())
+	}
+	if result.Error != "" {
+		outcome = append(outcome, "error: "+result.Error)
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s\n", result.Reference, strings.Join(outcome, " "))
+}
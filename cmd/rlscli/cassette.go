@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/SachinMeier/rls-client"
+	cli "github.com/urfave/cli"
+)
+
+// replayBaseURL is used in place of the configured URL when replaying a cassette
+const replayBaseURL = "http://replay.invalid"
+
+var (
+	recorder *rls.Recorder
+	replayer *rls.Replayer
+)
+
+// loadCassette sets up recording or replaying from the global --record and --replay flags
+func loadCassette(ctx *cli.Context) error {
+	if ctx.GlobalIsSet(flagRecord) && ctx.GlobalIsSet(flagReplay) {
+		return fmt.Errorf("--%s and --%s cannot be used together", flagRecord, flagReplay)
+	}
+	if ctx.GlobalIsSet(flagRecord) {
+		recorder = rls.NewRecorder()
+	}
+	if ctx.GlobalIsSet(flagReplay) {
+		cassette, err := rls.LoadCassette(ctx.GlobalString(flagReplay))
+		if err != nil {
+			return err
+		}
+		replayer = rls.NewReplayer(cassette, rls.MatchStrict)
+	}
+	return nil
+}
+
+// saveCassette writes the recorded interactions to the --record file
+func saveCassette(ctx *cli.Context) error {
+	if recorder == nil {
+		return nil
+	}
+	return recorder.Save(ctx.GlobalString(flagRecord))
+}
+
+// cassetteOptions returns the client options recording or replaying a cassette, if enabled
+func cassetteOptions() []rls.Option {
+	switch {
+	case recorder != nil:
+		return []rls.Option{rls.WithMiddleware(recorder.Middleware())}
+	case replayer != nil:
+		return []rls.Option{rls.WithMiddleware(replayer.Middleware())}
+	}
+	return nil
+}
@@ -11,47 +11,54 @@ import (
 )
 
 const (
-	rlsEnvKey           = "RLS_ENV"
-	rlsTLSPathKey       = "RLS_TLSPATH"
-	rlsURLKey           = "_URL"
-	rlsAccountIDKey     = "_RIVER_ACCOUNT_ID"
-	rlsAPISecretKey     = "_RIVER_API_SECRET"
-	rlsWebhookSecretKey = "_WEBHOOK_SECRET"
-	rlsHeadersKey       = "_HEADERS"
+	rlsEnvKey     = "RLS_ENV"
+	rlsTLSPathKey = "RLS_TLSPATH"
 )
 
 const msgFailedToLoadConfig string = "failed to load config : %s"
 
-func LoadRLSConfig() (*rls.Config, error) {
-	env := os.Getenv(rlsEnvKey)
-	baseURL := os.Getenv(env + rlsURLKey)
-	if baseURL == "" {
-		return nil, fmt.Errorf("%s not set", env+rlsURLKey)
-	}
-	accountID := os.Getenv(env + rlsAccountIDKey)
-	if accountID == "" {
-		return nil, fmt.Errorf("%s not set", env+rlsAccountIDKey)
-	}
-	apiKey := os.Getenv(env + rlsAPISecretKey)
-	if apiKey == "" {
-		return nil, fmt.Errorf("%s not set", env+rlsAPISecretKey)
+// LoadRLSConfig loads the profile selected by the global --config and --profile flags.
+// Environment variables prefixed with $RLS_ENV, e.g. SANDBOX_URL, or _URL when it is unset, override the profile.
+func LoadRLSConfig(cliCtx *cli.Context) (*rls.Profile, error) {
+	return rls.LoadProfile(loadOptions(cliCtx))
+}
+
+// loadOptions returns the config loading options of the global flags
+func loadOptions(cliCtx *cli.Context) rls.LoadOptions {
+	return rls.LoadOptions{
+		Path:       cliCtx.GlobalString(flagConfig),
+		Profile:    cliCtx.GlobalString(flagProfile),
+		EnvPrefix:  os.Getenv(rlsEnvKey),
+		Passphrase: keystorePassphrase,
 	}
-	// optionals
-	webhookSecret := os.Getenv(env + rlsWebhookSecretKey)
-	extraHeaders := parseExtraHeaders(make(map[string]string), os.Getenv(env+rlsHeadersKey))
-	return rls.NewConfig(baseURL, apiKey, accountID, webhookSecret, extraHeaders), nil
 }
 
 func NewRLSClient(ctx context.Context, cliCtx *cli.Context) (*rls.RLSClient, error) {
-	cfg, err := LoadRLSConfig()
+	profile, err := LoadRLSConfig(cliCtx)
+	if err != nil && replayer != nil {
+		// replaying does not reach RLS, so no credentials are needed
+		profile, err = &rls.Profile{BaseURL: replayBaseURL, AccountID: "replay"}, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf(msgFailedToLoadConfig, err)
 	}
+	cfg := profile.Config()
+	if cfg.ExtraHeaders == nil {
+		cfg.ExtraHeaders = make(map[string]string)
+	}
 	if cliCtx.GlobalIsSet(flagHeaders) {
 		cfg.ExtraHeaders = parseExtraHeaders(cfg.ExtraHeaders, cliCtx.GlobalString(flagHeaders))
 	}
-	httpClient := loadTLS(cliCtx)
-	return rls.NewRLSClient(ctx, *cfg, httpClient), nil
+	opts := []rls.Option{rls.WithContext(ctx)}
+	if cliCtx.GlobalBool(flagDebug) {
+		opts = append(opts, rls.WithLogger(rls.NewTextLogger(os.Stderr), rls.LogOptions{Bodies: true}))
+	}
+	if tlsOpts, ok := loadTLS(cliCtx, profile); ok {
+		opts = append(opts, rls.WithTLSOptions(tlsOpts))
+	}
+	// cassettes are innermost so they capture what is actually sent
+	opts = append(opts, cassetteOptions()...)
+	return rls.New(*cfg, opts...)
 }
 
 func parseExtraHeaders(headerMap map[string]string, headerStr string) map[string]string {
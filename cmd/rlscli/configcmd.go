@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SachinMeier/rls-client"
+	cli "github.com/urfave/cli"
+)
+
+var configCommand = cli.Command{
+	Name:     "config",
+	Category: "Config",
+	Usage:    "Shows and selects config file profiles",
+	Subcommands: []cli.Command{
+		{
+			Name:   "show",
+			Usage:  "Shows the resolved profile and where each setting comes from, without secrets",
+			Action: cliConfigShow,
+		},
+		{
+			Name:   "list",
+			Usage:  "Lists the profiles of the config file",
+			Action: cliConfigList,
+		},
+		{
+			Name:      "use",
+			Usage:     "Makes a profile the default in the config file",
+			ArgsUsage: "profile",
+			Action:    cliConfigUse,
+		},
+	},
+}
+
+func cliConfigShow(ctx *cli.Context) {
+	profile, err := LoadRLSConfig(ctx)
+	if err != nil {
+		fmt.Printf(msgFailedToLoadConfig+"\n", err.Error())
+		return
+	}
+	printProfile(profile)
+
+	if tlsOpts, ok := loadTLS(ctx, profile); ok && tlsOpts.CertFile != "" {
+		reloader, err := rls.NewCertReloader(tlsOpts.CertFile, tlsOpts.KeyFile)
+		if err != nil {
+			fmt.Printf("Error loading client certificate: %s\n", err.Error())
+			return
+		}
+		fmt.Printf("Client certificate %s expires %s\n", tlsOpts.CertFile, formatTimestamp(reloader.Expiry().Unix()))
+	}
+}
+
+func cliConfigList(ctx *cli.Context) {
+	file, err := rls.FindConfigFile(loadOptions(ctx))
+	if err != nil {
+		fmt.Printf(msgFailedToLoadConfig+"\n", err.Error())
+		return
+	}
+	if file == nil {
+		fmt.Printf("no config file found\n")
+		return
+	}
+	fmt.Printf("Profiles in %s:\n", file.Path)
+	for _, name := range file.Names() {
+		marker := " "
+		if name == file.DefaultProfile {
+			marker = "*"
+		}
+		fmt.Printf("%s %s\n", marker, name)
+	}
+}
+
+func cliConfigUse(ctx *cli.Context) {
+	if !ctx.Args().Present() {
+		fmt.Printf("profile must be passed as first argument\n")
+		return
+	}
+	name := ctx.Args().First()
+	file, err := rls.FindConfigFile(loadOptions(ctx))
+	if err != nil {
+		fmt.Printf(msgFailedToLoadConfig+"\n", err.Error())
+		return
+	}
+	if file == nil {
+		fmt.Printf("no config file found\n")
+		return
+	}
+	if err := file.Use(name); err != nil {
+		fmt.Printf("Error config use: %s\n", err.Error())
+		return
+	}
+	fmt.Printf("Default profile set to %s in %s\n", name, file.Path)
+}
+
+func printProfile(profile *rls.Profile) {
+	source := func(key string) string {
+		if src, ok := profile.Sources[key]; ok {
+			return "  (" + src + ")"
+		}
+		return ""
+	}
+	secret := func(value string) string {
+		if value == "" {
+			return "-"
+		}
+		return "<set>"
+	}
+	fmt.Printf("--- Profile: %s ---\n", profile.Name)
+	fmt.Printf("  Base URL:       %s%s\n", profile.BaseURL, source("base_url"))
+	fmt.Printf("  Account ID:     %s%s\n", profile.AccountID, source("account_id"))
+	fmt.Printf("  API Secret:     %s%s\n", secret(profile.APIKey), source("api_key"))
+	fmt.Printf("  Webhook Secret: %s%s\n", secret(profile.WebhookSecret), source("webhook_secret"))
+	if profile.TLSPath != "" {
+		fmt.Printf("  TLS Path:       %s%s\n", profile.TLSPath, source("tls_path"))
+	}
+	if len(profile.Headers) > 0 {
+		names := make([]string, 0, len(profile.Headers))
+		for name := range profile.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Printf("  Headers:        %s%s\n", strings.Join(names, ", "), source("headers"))
+	}
+	fmt.Printf("-----------------------------\n")
+}
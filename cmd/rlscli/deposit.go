@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/SachinMeier/rls-client"
 	cli "github.com/urfave/cli"
 )
 
@@ -14,9 +15,9 @@ var newInvoice = cli.Command{
 	Usage:     "Requests a new invoice from RLS",
 	ArgsUsage: "amt [label] [network]",
 	Flags: []cli.Flag{
-		cli.IntFlag{
+		cli.StringFlag{
 			Name:     flagAmt,
-			Usage:    "Amount of intended deposit in sats.",
+			Usage:    "Amount of intended deposit. Accepts units, e.g. 21000, 21k, 21000sat, 2100000msat or 0.00021btc (defaults to sats).",
 			Required: false,
 		},
 		cli.StringFlag{
@@ -29,6 +30,11 @@ var newInvoice = cli.Command{
 			Usage:    "Network (defaults to LN)",
 			Required: false,
 		},
+		cli.StringFlag{
+			Name:     flagCurrency,
+			Usage:    "Currency (defaults to BTC). Must be held by the account. Amounts in other currencies are integers in the currency's base unit.",
+			Required: false,
+		},
 	},
 	Description: `
 	Requests a new invoice from RLS.`,
@@ -44,20 +50,15 @@ func cliNewInvoice(ctx *cli.Context) {
 
 	args := ctx.Args()
 
-	var amount int64
-	var label, network string
+	var amountStr, label, network string
 
 	if ctx.IsSet(flagAmt) {
-		amount = ctx.Int64(flagAmt)
+		amountStr = ctx.String(flagAmt)
 	} else if args.Present() {
-		amount, err = strconv.ParseInt(args.First(), 10, 64)
-		if err != nil {
-			fmt.Printf("invalid amount: %s\n", err.Error())
-			return
-		}
+		amountStr = args.First()
 		args = args.Tail()
 	} else {
-		fmt.Printf("amount in sats (--%s) must be provided\n", flagAmt)
+		fmt.Printf("amount (--%s) must be provided\n", flagAmt)
 		return
 	}
 
@@ -71,7 +72,29 @@ func cliNewInvoice(ctx *cli.Context) {
 		network = networkLN
 	}
 
-	invoice, err := client.NewInvoice(amount, label, network)
+	currency := rls.CurrencyBTC
+	if ctx.IsSet(flagCurrency) {
+		currency = ctx.String(flagCurrency)
+	}
+
+	var invoice *rls.Invoice
+	if isBTC(currency) {
+		var amount rls.Amount
+		amount, err = rls.ParseAmount(amountStr)
+		if err != nil {
+			fmt.Printf("invalid amount: %s\n", err.Error())
+			return
+		}
+		invoice, err = client.NewInvoiceContext(client.Ctx, amount, label, network)
+	} else {
+		var amount int64
+		amount, err = parseBaseUnits(amountStr)
+		if err != nil {
+			fmt.Printf("invalid amount: %s\n", err.Error())
+			return
+		}
+		invoice, err = client.NewInvoiceWithCurrency(client.Ctx, currency, amount, label, network)
+	}
 	if err != nil {
 		fmt.Printf("Error NewInvoice: %s\n", err.Error())
 		return
@@ -118,7 +141,7 @@ func cliGetInvoice(ctx *cli.Context) {
 		}
 	}
 
-	invoice, err := client.GetInvoice(invID)
+	invoice, err := client.GetInvoiceContext(client.Ctx, invID)
 	if err != nil {
 		fmt.Printf("Error GetInvoice: %s\n", err.Error())
 		return
@@ -169,7 +192,7 @@ func cliGetDeposit(ctx *cli.Context) {
 		}
 	}
 
-	dep, err := client.GetDeposit(depID)
+	dep, err := client.GetDepositContext(client.Ctx, depID)
 	if err != nil {
 		fmt.Printf("Error GetDeposit: %s\n", err.Error())
 		return
@@ -238,7 +261,7 @@ func cliListDeposits(ctx *cli.Context) {
 		}
 	}
 
-	deps, err := client.GetDeposits(limit, nextTimestamp)
+	deps, err := client.GetDepositsContext(client.Ctx, limit, nextTimestamp)
 	if err != nil {
 		fmt.Printf("failed to list deposits: %s\n", err.Error())
 		return
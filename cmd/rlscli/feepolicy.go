@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/SachinMeier/rls-client"
+	cli "github.com/urfave/cli"
+)
+
+// feePolicyFlags are the flags choosing the fee limit of a payment
+var feePolicyFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  flagFeeLimit,
+		Usage: "Fee Limit, as an amount with the same units as --amt, a percentage of the amount, e.g. 0.5%, or ppm, e.g. 1000ppm+1sat (defaults to 300 sats).",
+	},
+	cli.Int64Flag{
+		Name:  flagFeePPM,
+		Usage: "Fee Limit in parts per million of the amount, plus --fee_base. Cannot be used with --fee_limit.",
+	},
+	cli.StringFlag{
+		Name:  flagFeeBase,
+		Usage: "Base fee added to --fee_ppm, with the same units as --amt.",
+	},
+}
+
+// feePolicy returns the fee policy chosen by feePolicyFlags, or rls.DefaultFeePolicy if none is set
+func feePolicy(ctx *cli.Context) (rls.FeePolicy, error) {
+	if ctx.IsSet(flagFeePPM) {
+		if ctx.IsSet(flagFeeLimit) {
+			return nil, fmt.Errorf("--%s and --%s cannot be used together", flagFeeLimit, flagFeePPM)
+		}
+		policy := rls.PPMFee{PPM: ctx.Int64(flagFeePPM)}
+		if policy.PPM < 0 || policy.PPM > rls.MaxFeePPM {
+			return nil, fmt.Errorf("invalid %s: must be between 0 and %d", flagFeePPM, rls.MaxFeePPM)
+		}
+		if ctx.IsSet(flagFeeBase) {
+			base, err := rls.ParseAmount(ctx.String(flagFeeBase))
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", flagFeeBase, err)
+			}
+			if base%rls.Satoshi != 0 {
+				return nil, fmt.Errorf("invalid %s: must be whole sats", flagFeeBase)
+			}
+			policy.Base = base
+		}
+		return policy, nil
+	}
+	if ctx.IsSet(flagFeeBase) {
+		return nil, fmt.Errorf("--%s requires --%s", flagFeeBase, flagFeePPM)
+	}
+	if ctx.IsSet(flagFeeLimit) {
+		policy, err := rls.ParseFeePolicy(ctx.String(flagFeeLimit))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", flagFeeLimit, err)
+		}
+		return policy, nil
+	}
+	return rls.DefaultFeePolicy, nil
+}
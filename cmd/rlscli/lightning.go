@@ -3,8 +3,8 @@ package main
 import (
 	"context"
 	"fmt"
-	"strconv"
 
+	"github.com/SachinMeier/rls-client"
 	cli "github.com/urfave/cli"
 )
 
@@ -45,7 +45,7 @@ func cliParseInvoice(ctx *cli.Context) error {
 		return fmt.Errorf("invoice must be set or passed as first argument")
 	}
 
-	decodedInvoice, err := client.DecodeInvoice(invoice)
+	decodedInvoice, err := client.DecodeInvoiceContext(client.Ctx, invoice)
 	if err != nil {
 		return err
 	}
@@ -58,20 +58,20 @@ var estimateLightningFee = cli.Command{
 	Category:  "Lightning",
 	Usage:     "Estimates fee to send to an amount to a specific node",
 	ArgsUsage: "invoice amount",
-	Flags: []cli.Flag{
+	Flags: append([]cli.Flag{
 		cli.StringFlag{
 			Name:     flagInvoice,
 			Usage:    "invoice to be parsed",
 			Required: false,
 		},
-		cli.Int64Flag{
+		cli.StringFlag{
 			Name:     flagAmt,
-			Usage:    "invoice to be parsed",
+			Usage:    "Amount to send. Accepts units, e.g. 21000, 21k, 21000sat, 2100000msat or 0.00021btc (defaults to sats).",
 			Required: false,
 		},
-	},
+	}, feePolicyFlags...),
 	Description: `
-	Parse a BOLT-11 invoice.
+	Estimates the fee to pay an invoice, and shows the fee limit the fee policy flags would choose.
 	`,
 	Action: cliEstimateLightningFee,
 }
@@ -85,7 +85,7 @@ func cliEstimateLightningFee(ctx *cli.Context) {
 
 	args := ctx.Args()
 
-	var amount int64
+	var amount rls.Amount
 	var invoice string
 
 	if ctx.IsSet(flagInvoice) {
@@ -99,23 +99,33 @@ func cliEstimateLightningFee(ctx *cli.Context) {
 	}
 
 	if ctx.IsSet(flagAmt) {
-		amount = ctx.Int64(flagAmt)
+		amount, err = rls.ParseAmount(ctx.String(flagAmt))
+		if err != nil {
+			fmt.Printf("invalid amount: %s\n", err.Error())
+			return
+		}
 	} else if args.Present() {
-		amount, err = strconv.ParseInt(args.First(), 10, 64)
+		amount, err = rls.ParseAmount(args.First())
 		if err != nil {
 			fmt.Printf("invalid amount: %s\n", err.Error())
 			return
 		}
 		args = args.Tail()
 	} else {
-		fmt.Printf("amount in sats must be provided")
+		fmt.Printf("amount must be provided")
+		return
+	}
+
+	policy, err := feePolicy(ctx)
+	if err != nil {
+		fmt.Printf("%s\n", err.Error())
 		return
 	}
 
-	feeEstimate, err := client.EstimateLightningFee(invoice, amount)
+	feeEstimate, err := client.EstimateLightningFeeContext(client.Ctx, invoice, amount)
 	if err != nil {
 		fmt.Printf("Error ParseInvoice: %s\n", err.Error())
 		return
 	}
-	printFeeEstimate(feeEstimate)
+	printFeeEstimate(feeEstimate, policy.FeeLimit(amount), policy)
 }
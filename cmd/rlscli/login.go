@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+
+	"github.com/SachinMeier/rls-client"
+	cli "github.com/urfave/cli"
+)
+
+const (
+	flagWebhookSecret = "webhook_secret"
+
+	rlsKeystorePassphraseKey = "RLS_KEYSTORE_PASSPHRASE"
+)
+
+var login = cli.Command{
+	Name:     "login",
+	Category: "Config",
+	Usage:    "Stores the API secret of the profile in the encrypted keystore",
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  flagWebhookSecret,
+			Usage: "Also prompts for the webhook secret",
+		},
+	},
+	Description: `
+	Prompts for the API secret of the selected profile without echoing it, and stores it in the
+	local keystore encrypted with a passphrase. Commands using the profile then prompt for the
+	passphrase, or read it from $RLS_KEYSTORE_PASSPHRASE.`,
+	Action: cliLogin,
+}
+
+var logout = cli.Command{
+	Name:     "logout",
+	Category: "Config",
+	Usage:    "Removes the secrets of the profile from the encrypted keystore",
+	Action:   cliLogout,
+}
+
+func cliLogin(ctx *cli.Context) {
+	name, keystore, err := profileKeystore(ctx)
+	if err != nil {
+		fmt.Printf("Error login: %s\n", err.Error())
+		return
+	}
+
+	apiKey, err := readSecret(fmt.Sprintf("API secret for profile %s: ", name))
+	if err != nil || apiKey == "" {
+		fmt.Printf("Error login: no API secret entered\n")
+		return
+	}
+	var webhookSecret string
+	if ctx.Bool(flagWebhookSecret) {
+		webhookSecret, err = readSecret(fmt.Sprintf("Webhook secret for profile %s: ", name))
+		if err != nil {
+			fmt.Printf("Error login: %s\n", err.Error())
+			return
+		}
+		if _, err := hex.DecodeString(webhookSecret); err != nil {
+			fmt.Printf("Error login: webhook secret is not hex encoded\n")
+			return
+		}
+	}
+
+	passphrase, err := readSecret("Keystore passphrase: ")
+	if err != nil || passphrase == "" {
+		fmt.Printf("Error login: no passphrase entered\n")
+		return
+	}
+	confirm, err := readSecret("Confirm passphrase: ")
+	if err != nil || confirm != passphrase {
+		fmt.Printf("Error login: passphrases do not match\n")
+		return
+	}
+
+	if err := keystore.Put(rls.KeystoreAPIKeyName(name), apiKey, []byte(passphrase)); err != nil {
+		fmt.Printf("Error login: %s\n", err.Error())
+		return
+	}
+	if webhookSecret != "" {
+		if err := keystore.Put(rls.KeystoreWebhookSecretName(name), webhookSecret, []byte(passphrase)); err != nil {
+			fmt.Printf("Error login: %s\n", err.Error())
+			return
+		}
+	}
+	fmt.Printf("Stored secrets of profile %s in %s\n", name, keystore.Path)
+}
+
+func cliLogout(ctx *cli.Context) {
+	name, keystore, err := profileKeystore(ctx)
+	if err != nil {
+		fmt.Printf("Error logout: %s\n", err.Error())
+		return
+	}
+	for _, entry := range []string{rls.KeystoreAPIKeyName(name), rls.KeystoreWebhookSecretName(name)} {
+		if err := keystore.Delete(entry); err != nil {
+			fmt.Printf("Error logout: %s\n", err.Error())
+			return
+		}
+	}
+	fmt.Printf("Removed secrets of profile %s from %s\n", name, keystore.Path)
+}
+
+// profileKeystore returns the name of the selected profile and the default keystore
+func profileKeystore(ctx *cli.Context) (string, *rls.Keystore, error) {
+	name, err := rls.ProfileName(loadOptions(ctx))
+	if err != nil {
+		return "", nil, err
+	}
+	path, err := rls.DefaultKeystorePath()
+	if err != nil {
+		return "", nil, err
+	}
+	return name, rls.OpenKeystore(path), nil
+}
+
+var (
+	passphraseOnce  sync.Once
+	passphraseValue []byte
+	passphraseErr   error
+)
+
+// keystorePassphrase returns $RLS_KEYSTORE_PASSPHRASE or prompts for the passphrase once per run
+func keystorePassphrase() ([]byte, error) {
+	passphraseOnce.Do(func() {
+		if passphrase := os.Getenv(rlsKeystorePassphraseKey); passphrase != "" {
+			passphraseValue = []byte(passphrase)
+			return
+		}
+		var passphrase string
+		passphrase, passphraseErr = readSecret("Keystore passphrase: ")
+		passphraseValue = []byte(passphrase)
+	})
+	return passphraseValue, passphraseErr
+}
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// readSecret prompts on stderr and reads a line from stdin. When stdin is a terminal, echo is
+// turned off while reading so the secret never appears on screen.
+func readSecret(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	if stty("-echo") == nil {
+		// restore echo if interrupted while reading
+		interrupted := make(chan os.Signal, 1)
+		signal.Notify(interrupted, os.Interrupt)
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-interrupted:
+				stty("echo")
+				fmt.Fprintln(os.Stderr)
+				os.Exit(130)
+			case <-done:
+			}
+		}()
+		defer func() {
+			close(done)
+			signal.Stop(interrupted)
+			stty("echo")
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read input : %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// stty changes the settings of the terminal on stdin. It fails if stdin is not a terminal.
+func stty(args ...string) error {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
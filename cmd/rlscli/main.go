@@ -13,6 +13,8 @@ const (
 	flagNetwork       = "network"
 	flagInvoice       = "invoice"
 	flagFeeLimit      = "fee_limit"
+	flagFeePPM        = "fee_ppm"
+	flagFeeBase       = "fee_base"
 	flagWithdrawalID  = "withdrawal_id"
 	flagInvoiceID     = "invoice_id"
 	flagDepositID     = "deposit_id"
@@ -21,7 +23,21 @@ const (
 	flagLimit         = "limit"
 	flagNextTimestamp = "next"
 	flagTLSPath       = "tlspath"
+	flagTLSCert       = "tls_cert"
+	flagTLSKey        = "tls_key"
+	flagTLSCA         = "tls_ca"
+	flagTLSPin        = "tls_pin"
+	flagTLSInsecure   = "tls_insecure"
 	flagHeaders       = "headers"
+	flagDebug         = "debug"
+	flagRecord        = "record"
+	flagReplay        = "replay"
+	flagUTC           = "utc"
+	flagLocal         = "local"
+	flagProfile       = "profile"
+	flagWait          = "wait"
+	flagTimeout       = "timeout"
+	flagConfig        = "config"
 
 	networkLN = "LN"
 )
@@ -39,7 +55,70 @@ func main() {
 			Usage:    "if set, loads TLS key and cert from <tlsPath>.key and <tlsPath>.cert and uses them in the HTTPS request",
 			Required: false,
 		},
+		cli.StringFlag{
+			Name:     flagTLSCert,
+			Usage:    "[Optional] client certificate (PEM) for mutual TLS, used with --tls_key instead of --tlspath",
+			Required: false,
+		},
+		cli.StringFlag{
+			Name:     flagTLSKey,
+			Usage:    "[Optional] client key (PEM) for mutual TLS, used with --tls_cert instead of --tlspath",
+			Required: false,
+		},
+		cli.StringFlag{
+			Name:     flagTLSCA,
+			Usage:    "[Optional] PEM bundle of the CAs trusted to verify the server, instead of the system roots",
+			Required: false,
+		},
+		cli.StringFlag{
+			Name:     flagTLSPin,
+			Usage:    "[Optional] comma-separated base64 SHA-256 hashes of the server public key (sha256//... is accepted)",
+			Required: false,
+		},
+		cli.BoolFlag{
+			Name:  flagTLSInsecure,
+			Usage: "[Optional] skips verification of the server certificate. Only for testing",
+		},
+		cli.BoolFlag{
+			Name:  flagDebug,
+			Usage: "[Optional] logs every request and response to stderr, with credentials redacted",
+		},
+		cli.StringFlag{
+			Name:     flagRecord,
+			Usage:    "[Optional] records every request and response to the given cassette file, with credentials scrubbed",
+			Required: false,
+		},
+		cli.StringFlag{
+			Name:     flagReplay,
+			Usage:    "[Optional] replays responses from the given cassette file instead of calling RLS",
+			Required: false,
+		},
+		cli.StringFlag{
+			Name:     flagProfile,
+			Usage:    "[Optional] profile of the config file to use. Defaults to $RLS_PROFILE, then the file's default_profile",
+			Required: false,
+		},
+		cli.StringFlag{
+			Name:     flagConfig,
+			Usage:    "[Optional] config file (.toml, .yaml or .json). Defaults to $RLS_CONFIG, then config.toml, config.yaml or config.json in the user config dir under rls/",
+			Required: false,
+		},
+		cli.BoolFlag{
+			Name:  flagUTC,
+			Usage: "[Optional] prints timestamps in UTC",
+		},
+		cli.BoolFlag{
+			Name:  flagLocal,
+			Usage: "[Optional] prints timestamps in the local time zone (default)",
+		},
+	}
+	app.Before = func(ctx *cli.Context) error {
+		if err := loadTimeLocation(ctx); err != nil {
+			return err
+		}
+		return loadCassette(ctx)
 	}
+	app.After = saveCassette
 	app.Name = "rlscli"
 	app.Usage = "River Financial's Enterprise Lightning API"
 	app.Commands = []cli.Command{
@@ -49,13 +128,19 @@ func main() {
 		getDeposit,
 		listDeposits,
 		newWithdrawal,
+		pay,
+		batchPay,
 		getWithdrawal,
 		listWithdrawals,
+		waitWithdrawal,
 		newWebhook,
 		getWebhook,
 		rmWebhook,
 		parseInvoice,
 		estimateLightningFee,
+		configCommand,
+		login,
+		logout,
 	}
 
 	if err := app.Run(os.Args); err != nil {
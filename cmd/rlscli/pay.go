@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/SachinMeier/rls-client"
+	cli "github.com/urfave/cli"
+)
+
+var pay = cli.Command{
+	Name:      "pay",
+	Category:  "Withdrawals",
+	Usage:     "Checks and pays an invoice",
+	ArgsUsage: "invoice",
+	Flags: append([]cli.Flag{
+		cli.StringFlag{
+			Name:     flagInvoice,
+			Usage:    "BOLT-11 Invoice for RLS to pay",
+			Required: false,
+		},
+		cli.StringFlag{
+			Name:     flagAmt,
+			Usage:    "Amount to pay, required for amountless invoices. Accepts units, e.g. 21000, 21k, 21000sat, 2100000msat or 0.00021btc (defaults to sats).",
+			Required: false,
+		},
+		cli.StringFlag{
+			Name:     flagCurrency,
+			Usage:    "Currency (defaults to BTC). Only BTC is supported.",
+			Required: false,
+		},
+		cli.BoolFlag{
+			Name:  flagWait,
+			Usage: "Waits until the withdrawal succeeds or fails",
+		},
+		cli.DurationFlag{
+			Name:  flagTimeout,
+			Usage: "With --wait, gives up waiting after this long, e.g. 5m (defaults to no limit)",
+		},
+	}, feePolicyFlags...),
+	Description: `
+	Decodes the invoice, checks the amount and that the available balance covers it with the fee
+	limit, estimates the fee and refuses to pay if it exceeds the fee limit, then submits the
+	withdrawal. The fee estimate and the chosen fee limit are printed before submission.
+	Unlike newwithdrawal, nothing is submitted unless every check passes.
+	`,
+	Action: cliPay,
+}
+
+func cliPay(ctx *cli.Context) {
+	client, err := NewRLSClient(context.Background(), ctx)
+	if err != nil {
+		errFailedToCreateRLSClient(err)
+		return
+	}
+
+	var invoice string
+	if ctx.IsSet(flagInvoice) {
+		invoice = ctx.String(flagInvoice)
+	} else if ctx.Args().Present() {
+		invoice = ctx.Args().First()
+	} else {
+		fmt.Printf("invoice must be set or passed as first argument\n")
+		return
+	}
+
+	opts := rls.PayOptions{
+		Currency: ctx.String(flagCurrency),
+		Wait:     ctx.Bool(flagWait),
+	}
+	if ctx.IsSet(flagAmt) {
+		opts.Amount, err = rls.ParseAmount(ctx.String(flagAmt))
+		if err != nil {
+			fmt.Printf("invalid amount: %s\n", err.Error())
+			return
+		}
+	}
+	opts.FeePolicy, err = feePolicy(ctx)
+	if err != nil {
+		fmt.Printf("%s\n", err.Error())
+		return
+	}
+	checked := false
+	opts.Confirm = func(result *rls.PaymentResult) error {
+		printPaymentChecks(result, opts.FeePolicy)
+		checked = true
+		return nil
+	}
+	if opts.Wait {
+		var lastState rls.WithdrawalState
+		opts.WaitOptions = rls.WaitOptions{
+			Timeout: ctx.Duration(flagTimeout),
+			OnUpdate: func(wd *rls.Withdrawal) {
+				if wd.State != lastState {
+					fmt.Fprintf(os.Stderr, "withdrawal %s is %s\n", wd.ID, wd.State)
+					lastState = wd.State
+				}
+			},
+		}
+	}
+
+	result, err := client.Pay(client.Ctx, invoice, opts)
+	if !checked {
+		printPaymentChecks(result, opts.FeePolicy)
+	}
+	if result.Withdrawal != nil {
+		printWithdrawal(result.Withdrawal)
+	}
+	if err != nil {
+		fmt.Printf("Error Pay: %s\n", err.Error())
+	}
+}
@@ -2,22 +2,43 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"text/tabwriter"
 
 	"github.com/SachinMeier/rls-client"
 )
 
+// formatState annotates a withdrawal or deposit state with whether it is final
+func formatState(state string, known, terminal bool) string {
+	switch {
+	case !known:
+		return state + " (unknown state)"
+	case terminal:
+		return state + " (final)"
+	default:
+		return state + " (in progress)"
+	}
+}
+
 func printAccount(acct *rls.Account) {
 	fmt.Printf("--- Account: %s ---\n", acct.ID)
-	fmt.Printf("  Total Balance:     %d sats\n", acct.Balance)
-	fmt.Printf("  Available Balance: %d sats\n", acct.AvailableBalance)
-	fmt.Printf("  Reserved Balance:  %d sats\n", acct.GetReservedBalance())
+	fmt.Printf("  Total Balance:     %s\n", acct.Balance)
+	fmt.Printf("  Available Balance: %s\n", acct.AvailableBalance)
+	fmt.Printf("  Reserved Balance:  %s\n", acct.GetReservedBalance())
+	fmt.Printf("  Balances by currency:\n")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "    Currency\tBalance\tOn Hold\tAvailable\t\n")
+	for _, currency := range acct.Currencies() {
+		fmt.Fprintf(tw, "    %s\t%d\t%d\t%d\t\n", currency, acct.BalanceOf(currency), acct.OnHoldOf(currency), acct.AvailableOf(currency))
+	}
+	tw.Flush()
 	fmt.Printf("-----------------------------\n")
 }
 
 func printDepositInvoice(inv *rls.Invoice) {
 	fmt.Printf("--- Deposit Invoice: %s ---\n", inv.ID)
 	fmt.Printf("  Network:    %s\n", inv.Network)
-	fmt.Printf("  Timestamp:  %d\n", inv.Timestamp)
+	fmt.Printf("  Timestamp:  %s\n", formatTimestamp(inv.Timestamp))
 	fmt.Printf("  Invoice: %s\n", inv.Invoice)
 	fmt.Printf("-------------------------------------\n")
 }
@@ -25,11 +46,12 @@ func printDepositInvoice(inv *rls.Invoice) {
 func printWithdrawal(wd *rls.Withdrawal) {
 	fmt.Printf("----- Withdrawal: %s -----\n", wd.ID)
 	fmt.Printf("  Currency/Network: %s/%s\n", wd.Currency, wd.Network())
-	fmt.Printf("  State:            %s\n", wd.State)
+	fmt.Printf("  State:            %s\n", formatState(string(wd.State), wd.State.IsKnown(), wd.State.IsTerminal()))
 	fmt.Printf("  Invoice: %s\n", wd.Invoice())
-	fmt.Printf("  Fee Limit: %d\n", wd.FeeLimit())
-	fmt.Printf("  Fee Paid: %d\n", wd.FeePaid)
-	fmt.Printf("  Timestamp: %d\n", wd.Timestamp)
+	fmt.Printf("  Amount:    %s\n", wd.Amount)
+	fmt.Printf("  Fee Limit: %s\n", wd.FeeLimit())
+	fmt.Printf("  Fee Paid: %s\n", wd.FeePaid)
+	fmt.Printf("  Timestamp: %s\n", formatTimestamp(wd.Timestamp))
 	fmt.Printf("-------------------------------------\n")
 }
 
@@ -37,16 +59,16 @@ func printWithdrawalList(wds *rls.WithdrawalList) {
 	for _, withdrawal := range wds.Withdrawals {
 		printWithdrawal(&withdrawal)
 	}
-	fmt.Printf("Next Timestamp: %d\n", wds.NextTimestamp)
+	fmt.Printf("Next Timestamp: %d %s\n", wds.NextTimestamp, formatTimestamp(wds.NextTimestamp))
 	fmt.Printf("-------------------------------------\n")
 }
 
 func printDeposit(dep *rls.Deposit) {
 	fmt.Printf("--- Deposit: %s ---\n", dep.ID)
-	fmt.Printf("  Amount:     %d\n", dep.Amount)
-	fmt.Printf("  State:      %s\n", dep.State)
+	fmt.Printf("  Amount:     %s\n", dep.Amount)
+	fmt.Printf("  State:      %s\n", formatState(string(dep.State), dep.State.IsKnown(), dep.State.IsTerminal()))
 	fmt.Printf("  Network:    %s\n", dep.Detail.Network)
-	fmt.Printf("  Timestamp:  %d\n", dep.Timestamp)
+	fmt.Printf("  Timestamp:  %s\n", formatTimestamp(dep.Timestamp))
 	fmt.Printf("  Invoice ID: %s\n", dep.Invoice.ID)
 	fmt.Printf("  Invoice:    %s\n", dep.Invoice.Invoice)
 	fmt.Printf("-------------------------------------\n")
@@ -56,7 +78,7 @@ func printDepositList(deps *rls.DepositList) {
 	for _, deposit := range deps.Deposits {
 		printDeposit(&deposit)
 	}
-	fmt.Printf("Next Timestamp: %d\n", deps.NextTimestamp)
+	fmt.Printf("Next Timestamp: %d %s\n", deps.NextTimestamp, formatTimestamp(deps.NextTimestamp))
 	fmt.Printf("-------------------------------------\n")
 }
 
@@ -72,21 +94,76 @@ func printWebhook(wh *rls.Webhook) {
 
 func printInvoice(invoice *rls.DecodedInvoice) {
 	fmt.Printf("--- Invoice ---\n")
-	fmt.Printf("  Amount: %d\n", invoice.Amount)
+	fmt.Printf("  Amount: %s\n", invoice.Amount)
 	fmt.Printf("  Destination: %s\n", invoice.NodeID)
 	fmt.Printf("  Memo: %s\n", invoice.Memo)
 	// fmt.Printf("  Invoice: %s\n", invoice.Invoice)
 	fmt.Printf("---------------\n")
 }
 
-func printFeeEstimate(feeEstimate *rls.FeeEstimate) {
+// printFeeEstimate prints a fee estimate against feeLimit, the limit chosen by policy
+func printFeeEstimate(feeEstimate *rls.FeeEstimate, feeLimit rls.Amount, policy rls.FeePolicy) {
 	fmt.Printf("--- Fee Estimate ---\n")
-	fmt.Printf("  Fee Estimate: %d\n", feeEstimate.Fee)
-	fmt.Printf("  Amount: %d\n", feeEstimate.Amount)
+	fmt.Printf("  Fee Estimate: %s\n", feeEstimate.Fee)
+	fmt.Printf("  Fee Limit: %s (%v)\n", feeLimit, policy)
+	if feeEstimate.Fee > feeLimit {
+		fmt.Printf("  Warning: estimate exceeds fee limit\n")
+	}
+	fmt.Printf("  Amount: %s\n", feeEstimate.Amount)
 	// fmt.Printf("  Invoice: %s\n", feeEstimate.Invoice)
 	fmt.Printf("---------------\n")
 }
 
+// printPaymentChecks prints the checks of a payment that completed, with the fee limit Pay checked
+func printPaymentChecks(result *rls.PaymentResult, policy rls.FeePolicy) {
+	if result.Invoice != nil {
+		printInvoice(result.Invoice)
+	}
+	if result.Account != nil {
+		fmt.Printf("--- Balance Check ---\n")
+		fmt.Printf("  Available Balance: %s\n", result.Account.AvailableBalance)
+		fmt.Printf("  Amount: %s\n", result.Amount)
+		fmt.Printf("  Fee Limit: %s\n", result.FeeLimit)
+		fmt.Printf("---------------\n")
+	}
+	if result.FeeEstimate != nil {
+		printFeeEstimate(result.FeeEstimate, result.FeeLimit, policy)
+	}
+}
+
+func printBatchPlan(plan []rls.BatchResult) {
+	var total rls.Amount
+	toPay := 0
+	fmt.Printf("--- Batch ---\n")
+	for _, result := range plan {
+		if result.WithdrawalID != "" {
+			fmt.Printf("  %s: already submitted as %s (%s)\n", result.Reference, result.WithdrawalID, result.State)
+			continue
+		}
+		toPay++
+		total += result.Amount + result.FeeLimit
+		fmt.Printf("  %s: %s, fee limit %s\n", result.Reference, result.Amount, result.FeeLimit)
+	}
+	fmt.Printf("  Rows to pay: %d\n", toPay)
+	fmt.Printf("  Total with fee limits: %s\n", total)
+	fmt.Printf("---------------\n")
+}
+
+func printBatchReport(report *rls.BatchReport, resultsPath string) {
+	fmt.Printf("--- Batch ---\n")
+	fmt.Printf("  Succeeded: %d\n", report.Succeeded)
+	fmt.Printf("  Failed: %d\n", report.Failed)
+	fmt.Printf("  Pending: %d\n", report.Pending)
+	fmt.Printf("  Errors: %d\n", report.Errors)
+	if report.NotStarted > 0 {
+		fmt.Printf("  Not Started: %d\n", report.NotStarted)
+	}
+	fmt.Printf("  Paid: %s\n", report.Paid)
+	fmt.Printf("  Fees: %s\n", report.Fees)
+	fmt.Printf("  Results: %s\n", resultsPath)
+	fmt.Printf("---------------\n")
+}
+
 func errFailedToCreateRLSClient(err error) {
 	fmt.Printf("failed to load RLS client: %s\n", err.Error())
 }
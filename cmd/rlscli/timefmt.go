@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SachinMeier/rls-client"
+	cli "github.com/urfave/cli"
+)
+
+// displayLocation is the time zone timestamps are printed in, set by the global --utc and --local flags
+var displayLocation = time.Local
+
+// loadTimeLocation sets displayLocation from the global --utc and --local flags
+func loadTimeLocation(ctx *cli.Context) error {
+	if ctx.GlobalBool(flagUTC) && ctx.GlobalBool(flagLocal) {
+		return fmt.Errorf("--%s and --%s cannot be used together", flagUTC, flagLocal)
+	}
+	if ctx.GlobalBool(flagUTC) {
+		displayLocation = time.UTC
+	}
+	return nil
+}
+
+// formatTimestamp formats an RLS timestamp as RFC3339 in displayLocation followed by how long ago it was
+func formatTimestamp(ts int64) string {
+	if ts == 0 {
+		return "-"
+	}
+	t := rls.TimeFromTimestamp(ts)
+	return fmt.Sprintf("%s (%s)", t.In(displayLocation).Format(time.RFC3339), formatRelative(time.Since(t)))
+}
+
+// formatRelative formats a duration since a past time as "3m ago", or a negative one as "in 3m"
+func formatRelative(d time.Duration) string {
+	if d < 0 {
+		return "in " + formatShortDuration(-d)
+	}
+	if d < time.Second {
+		return "just now"
+	}
+	return formatShortDuration(d) + " ago"
+}
+
+// formatShortDuration formats d in its largest whole unit, e.g. "45s", "3m", "5h" or "2d"
+func formatShortDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int64(d/time.Second))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int64(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int64(d/time.Hour))
+	default:
+		return fmt.Sprintf("%dd", int64(d/(24*time.Hour)))
+	}
+}
@@ -1,41 +1,38 @@
 package main
 
 import (
-	"crypto/tls"
-	"log"
-	"net/http"
 	"os"
+	"strings"
 
+	"github.com/SachinMeier/rls-client"
 	cli "github.com/urfave/cli"
 )
 
-func loadTLS(ctx *cli.Context) *http.Client {
-	if ctx.GlobalIsSet(flagTLSPath) {
-		tlsPath := ctx.GlobalString(flagTLSPath)
-		return loadCertAndKey(tlsPath)
-	} else {
-		tlsPath := os.Getenv(rlsTLSPathKey)
-		if tlsPath != "" {
-			return loadCertAndKey(tlsPath)
-		}
-		return http.DefaultClient
+// loadTLS returns the TLS options from the global TLS flags and the profile, and whether any are set.
+// Flags take precedence over the profile. --tlspath, then the profile's tls_path, then $RLS_TLSPATH
+// are used when no separate certificate and key are given.
+func loadTLS(ctx *cli.Context, profile *rls.Profile) (rls.TLSOptions, bool) {
+	opts := profile.TLSOptions()
+	if ctx.GlobalIsSet(flagTLSCert) || ctx.GlobalIsSet(flagTLSKey) {
+		opts.CertFile, opts.KeyFile = ctx.GlobalString(flagTLSCert), ctx.GlobalString(flagTLSKey)
+	} else if ctx.GlobalIsSet(flagTLSPath) {
+		opts.CertFile, opts.KeyFile = rls.TLSPathFiles(ctx.GlobalString(flagTLSPath))
+	} else if tlsPath := os.Getenv(rlsTLSPathKey); opts.CertFile == "" && tlsPath != "" {
+		opts.CertFile, opts.KeyFile = rls.TLSPathFiles(tlsPath)
 	}
-}
-
-func loadCertAndKey(tlsPath string) *http.Client {
-	clientCert := tlsPath + ".cert"
-	clientKey := tlsPath + ".key"
-
-	cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
-	if err != nil {
-		log.Fatalf("failed to load x509 certs: %v", err)
+	if ctx.GlobalIsSet(flagTLSCA) {
+		opts.CAFile = ctx.GlobalString(flagTLSCA)
 	}
-	return &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-				Certificates:       []tls.Certificate{cert},
-			},
-		},
+	if ctx.GlobalIsSet(flagTLSPin) {
+		opts.PinnedSPKI = nil
+		for _, pin := range strings.Split(ctx.GlobalString(flagTLSPin), ",") {
+			if pin = strings.TrimSpace(pin); pin != "" {
+				opts.PinnedSPKI = append(opts.PinnedSPKI, pin)
+			}
+		}
 	}
+	opts.InsecureSkipVerify = ctx.GlobalBool(flagTLSInsecure)
+
+	set := opts.CertFile != "" || opts.KeyFile != "" || opts.CAFile != "" || len(opts.PinnedSPKI) > 0 || opts.InsecureSkipVerify
+	return opts, set
 }
@@ -44,7 +44,7 @@ func cliNewWebhook(ctx *cli.Context) {
 		fmt.Printf("url flag must be set or argument must be passed\n")
 	}
 
-	webhook, err := client.SubscribeToWebhook(url)
+	webhook, err := client.SubscribeToWebhookContext(client.Ctx, url)
 	if err != nil {
 		fmt.Printf("failed to subscribe to webhook: %s\n", err.Error())
 		return
@@ -69,7 +69,7 @@ func cliGetWebhook(ctx *cli.Context) {
 		return
 	}
 
-	webhook, err := client.GetSubscribedWebhook()
+	webhook, err := client.GetSubscribedWebhookContext(client.Ctx)
 	if err != nil {
 		fmt.Printf("failed to get webhook: %s\n", err.Error())
 		return
@@ -120,7 +120,7 @@ func cliDeleteWebhook(ctx *cli.Context) {
 		return
 	}
 
-	err = client.DeleteWebhook(url)
+	err = client.DeleteWebhookContext(client.Ctx, url)
 	if err != nil {
 		fmt.Printf("failed to delete webhook: %s\n", err.Error())
 		return
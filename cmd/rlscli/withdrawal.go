@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
 	"strconv"
+	"time"
 
 	"github.com/SachinMeier/rls-client"
 	cli "github.com/urfave/cli"
@@ -14,10 +16,10 @@ var newWithdrawal = cli.Command{
 	Category:  "Withdrawals",
 	Usage:     "Requests a payment to the specified invoice from RLS",
 	ArgsUsage: "amt [label] [network]",
-	Flags: []cli.Flag{
-		cli.IntFlag{
+	Flags: append([]cli.Flag{
+		cli.StringFlag{
 			Name:     flagAmt,
-			Usage:    "Amount of intended withdrawal in sats.",
+			Usage:    "Amount of intended withdrawal. Accepts units, e.g. 21000, 21k, 21000sat, 2100000msat or 0.00021btc (defaults to sats).",
 			Required: true,
 		},
 		cli.StringFlag{
@@ -25,11 +27,6 @@ var newWithdrawal = cli.Command{
 			Usage:    "BOLT-11 Invoice for RLS to pay",
 			Required: true,
 		},
-		cli.StringFlag{
-			Name:     flagFeeLimit,
-			Usage:    "Fee Limit for the withdrawal.",
-			Required: true,
-		},
 		cli.StringFlag{
 			Name:     flagNetwork,
 			Usage:    "Network (defaults to LN)",
@@ -37,12 +34,21 @@ var newWithdrawal = cli.Command{
 		},
 		cli.StringFlag{
 			Name:     flagCurrency,
-			Usage:    "Currency (defaults to BTC)",
+			Usage:    "Currency (defaults to BTC). Must be held by the account. Amounts in other currencies are integers in the currency's base unit.",
 			Required: false,
 		},
-	},
+		cli.BoolFlag{
+			Name:  flagWait,
+			Usage: "Waits until the withdrawal succeeds or fails",
+		},
+		cli.DurationFlag{
+			Name:  flagTimeout,
+			Usage: "With --wait, gives up waiting after this long, e.g. 5m (defaults to no limit)",
+		},
+	}, feePolicyFlags...),
 	Description: `
-	Requests a payment to the specified invoice from RLS.
+	Requests a payment to the specified invoice from RLS. The invoice is submitted as is;
+	use pay to decode it and check the balance and fee estimate first.
 	`,
 	Action: cliNewWithdrawal,
 }
@@ -56,8 +62,7 @@ func cliNewWithdrawal(ctx *cli.Context) {
 
 	args := ctx.Args()
 
-	var amount, feeLimit int64
-	var invoice string
+	var amountStr, invoice string
 
 	if ctx.IsSet(flagInvoice) {
 		invoice = ctx.String(flagInvoice)
@@ -70,40 +75,125 @@ func cliNewWithdrawal(ctx *cli.Context) {
 	}
 
 	if ctx.IsSet(flagAmt) {
-		amount = ctx.Int64(flagAmt)
+		amountStr = ctx.String(flagAmt)
 	} else if args.Present() {
-		amount, err = strconv.ParseInt(args.First(), 10, 64)
-		if err != nil {
-			fmt.Printf("invalid amount: %s\n", err.Error())
-			return
-		}
+		amountStr = args.First()
 		args = args.Tail()
 	} else {
-		fmt.Printf("amount in sats must be provided\n")
+		fmt.Printf("amount must be provided\n")
 		return
 	}
 
-	if ctx.IsSet(flagFeeLimit) {
-		feeLimit = ctx.Int64(flagFeeLimit)
-	} else if args.Present() {
-		feeLimit, err = strconv.ParseInt(args.First(), 10, 64)
+	policy, err := feePolicy(ctx)
+	if err != nil {
+		fmt.Printf("%s\n", err.Error())
+		return
+	}
+	if !ctx.IsSet(flagFeeLimit) && !ctx.IsSet(flagFeePPM) && args.Present() {
+		policy, err = rls.ParseFeePolicy(args.First())
 		if err != nil {
 			fmt.Printf("invalid fee_limit: %s\n", err.Error())
 			return
 		}
-		args = args.Tail()
-	} else {
-		feeLimit = rls.DefaultFeeLimit
 	}
 
-	wd := rls.NewWithdrawalWithFeeLimit(amount, invoice, feeLimit)
+	currency := rls.CurrencyBTC
+	if ctx.IsSet(flagCurrency) {
+		currency = ctx.String(flagCurrency)
+	}
+
+	var wd *rls.Withdrawal
+	if isBTC(currency) {
+		amount, err := rls.ParseAmount(amountStr)
+		if err != nil {
+			fmt.Printf("invalid amount: %s\n", err.Error())
+			return
+		}
+		wd = rls.NewWithdrawalFromAmount(amount, invoice, policy.FeeLimit(amount))
+	} else {
+		amount, err := parseBaseUnits(amountStr)
+		if err != nil {
+			fmt.Printf("invalid amount: %s\n", err.Error())
+			return
+		}
+		// fee policies scale with the amount, so they are applied to the base units as if they were sats
+		wd = rls.NewWithdrawalWithCurrency(currency, amount, invoice, policy.FeeLimit(rls.Sats(amount)).Sats())
+	}
 
-	withdrawal, err := client.NewWithdrawal(wd)
+	withdrawal, err := client.NewWithdrawalContext(client.Ctx, wd)
 	if err != nil {
 		fmt.Printf("Error NewWithdrawal: %s\n", err.Error())
 		return
 	}
-	printWithdrawal(withdrawal)
+	if !ctx.Bool(flagWait) {
+		printWithdrawal(withdrawal)
+		return
+	}
+	waitForWithdrawal(client, withdrawal.ID, ctx.Duration(flagTimeout))
+}
+
+var waitWithdrawal = cli.Command{
+	Name:      "waitwithdrawal",
+	Category:  "Withdrawals",
+	Usage:     "Waits until a withdrawal succeeds or fails",
+	ArgsUsage: flagWithdrawalID,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:     flagWithdrawalID,
+			Usage:    "Withdrawal ID to wait for.",
+			Required: false,
+		},
+		cli.DurationFlag{
+			Name:  flagTimeout,
+			Usage: "Gives up waiting after this long, e.g. 5m (defaults to no limit)",
+		},
+	},
+	Description: `
+	Polls a withdrawal with backoff until it reaches SUCCESS or FAIL, printing state changes.
+	`,
+	Action: cliWaitWithdrawal,
+}
+
+func cliWaitWithdrawal(ctx *cli.Context) {
+	client, err := NewRLSClient(context.Background(), ctx)
+	if err != nil {
+		errFailedToCreateRLSClient(err)
+		return
+	}
+
+	var wdID string
+
+	if ctx.IsSet(flagWithdrawalID) {
+		wdID = ctx.String(flagWithdrawalID)
+	} else {
+		wdID = ctx.Args().First()
+		if wdID == "" {
+			fmt.Printf("withdrawal_id must be set\n")
+			return
+		}
+	}
+
+	waitForWithdrawal(client, wdID, ctx.Duration(flagTimeout))
+}
+
+// waitForWithdrawal waits for the withdrawal to finish, printing each state change to stderr
+func waitForWithdrawal(client *rls.RLSClient, withdrawalID string, timeout time.Duration) {
+	var lastState rls.WithdrawalState
+	wd, err := client.WaitForWithdrawal(client.Ctx, withdrawalID, rls.WaitOptions{
+		Timeout: timeout,
+		OnUpdate: func(wd *rls.Withdrawal) {
+			if wd.State != lastState {
+				fmt.Fprintf(os.Stderr, "withdrawal %s is %s\n", wd.ID, wd.State)
+				lastState = wd.State
+			}
+		},
+	})
+	if wd != nil {
+		printWithdrawal(wd)
+	}
+	if err != nil {
+		fmt.Printf("Error WaitForWithdrawal: %s\n", err.Error())
+	}
 }
 
 var getWithdrawal = cli.Command{
@@ -143,7 +233,7 @@ func cliGetWithdrawal(ctx *cli.Context) {
 		}
 	}
 
-	wd, err := client.GetWithdrawal(wdID)
+	wd, err := client.GetWithdrawalContext(client.Ctx, wdID)
 	if err != nil {
 		fmt.Printf("Error cliGetWithdrawal: %s\n", err.Error())
 		return
@@ -208,7 +298,7 @@ func cliListWithdrawals(ctx *cli.Context) {
 		}
 	}
 
-	wds, err := client.ListWithdrawals(limit, nextTimestamp)
+	wds, err := client.ListWithdrawalsContext(client.Ctx, limit, nextTimestamp)
 	if err != nil {
 		fmt.Printf("Error cliGetWithdrawal: %s\n", err.Error())
 		return
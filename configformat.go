@@ -0,0 +1,301 @@
+package rls
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The config file parsers below support the subset of TOML and YAML needed for config files:
+// nested tables/mappings of string values. Arrays and multi-line values are not supported.
+
+var bareKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// parseTOML parses a TOML document of tables, dotted keys, inline tables and scalar values
+func parseTOML(data []byte) (map[string]interface{}, error) {
+	root := make(map[string]interface{})
+	current := root
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(stripComment(line, false))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("line %d : arrays of tables are not supported", i+1)
+			}
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d : unterminated table header", i+1)
+			}
+			keys, err := splitTOMLKey(line[1 : len(line)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d : %w", i+1, err)
+			}
+			if current, err = descend(root, keys); err != nil {
+				return nil, fmt.Errorf("line %d : %w", i+1, err)
+			}
+			continue
+		}
+		if err := setTOMLPair(current, line); err != nil {
+			return nil, fmt.Errorf("line %d : %w", i+1, err)
+		}
+	}
+	return root, nil
+}
+
+// setTOMLPair parses a key = value pair into table
+func setTOMLPair(table map[string]interface{}, pair string) error {
+	eq := indexUnquoted(pair, '=')
+	if eq < 0 {
+		return fmt.Errorf("expected key = value, got %q", pair)
+	}
+	keys, err := splitTOMLKey(pair[:eq])
+	if err != nil {
+		return err
+	}
+	value, err := parseTOMLValue(strings.TrimSpace(pair[eq+1:]))
+	if err != nil {
+		return err
+	}
+	parent, err := descend(table, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	key := keys[len(keys)-1]
+	if _, ok := parent[key]; ok {
+		return fmt.Errorf("duplicate key %q", key)
+	}
+	parent[key] = value
+	return nil
+}
+
+// parseTOMLValue parses a string, inline table, boolean or number. Booleans and numbers are kept as strings.
+func parseTOMLValue(value string) (interface{}, error) {
+	switch {
+	case value == "":
+		return nil, fmt.Errorf("missing value")
+	case strings.HasPrefix(value, `"`):
+		return strconv.Unquote(value)
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return nil, fmt.Errorf("unterminated string %s", value)
+		}
+		return value[1 : len(value)-1], nil
+	case strings.HasPrefix(value, "{"):
+		if !strings.HasSuffix(value, "}") {
+			return nil, fmt.Errorf("unterminated inline table %s", value)
+		}
+		table := make(map[string]interface{})
+		for _, pair := range splitUnquoted(value[1:len(value)-1], ',') {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			if err := setTOMLPair(table, pair); err != nil {
+				return nil, err
+			}
+		}
+		return table, nil
+	case strings.HasPrefix(value, "["):
+		return nil, fmt.Errorf("arrays are not supported")
+	default:
+		return value, nil
+	}
+}
+
+// splitTOMLKey splits a dotted key such as profiles."my prod".base_url
+func splitTOMLKey(key string) ([]string, error) {
+	var keys []string
+	for _, part := range splitUnquoted(key, '.') {
+		part = strings.TrimSpace(part)
+		switch {
+		case strings.HasPrefix(part, `"`):
+			unquoted, err := strconv.Unquote(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key %s : %w", key, err)
+			}
+			part = unquoted
+		case strings.HasPrefix(part, "'") && len(part) >= 2 && strings.HasSuffix(part, "'"):
+			part = part[1 : len(part)-1]
+		case !bareKeyPattern.MatchString(part):
+			return nil, fmt.Errorf("invalid key %q", key)
+		}
+		keys = append(keys, part)
+	}
+	return keys, nil
+}
+
+// parseYAML parses a YAML document of nested block mappings and scalar values
+func parseYAML(data []byte) (map[string]interface{}, error) {
+	type frame struct {
+		indent int
+		table  map[string]interface{}
+	}
+	root := make(map[string]interface{})
+	stack := []frame{{indent: 0, table: root}}
+	// pending is a key whose value is a nested mapping starting on the next line
+	var pending string
+	var pendingTable map[string]interface{}
+
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(stripComment(line, true), " \t\r")
+		content := strings.TrimLeft(line, " ")
+		if content == "" || content == "---" {
+			continue
+		}
+		if strings.HasPrefix(content, "\t") {
+			return nil, fmt.Errorf("line %d : tabs are not allowed in indentation", i+1)
+		}
+		indent := len(line) - len(content)
+
+		if pendingTable != nil {
+			if indent > stack[len(stack)-1].indent {
+				table := make(map[string]interface{})
+				pendingTable[pending] = table
+				stack = append(stack, frame{indent: indent, table: table})
+			} else {
+				pendingTable[pending] = ""
+			}
+			pendingTable = nil
+		}
+		for len(stack) > 1 && indent < stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+		top := stack[len(stack)-1]
+		if indent != top.indent {
+			return nil, fmt.Errorf("line %d : unexpected indentation", i+1)
+		}
+		if strings.HasPrefix(content, "- ") || content == "-" {
+			return nil, fmt.Errorf("line %d : lists are not supported", i+1)
+		}
+
+		colon := yamlKeyEnd(content)
+		if colon < 0 {
+			return nil, fmt.Errorf("line %d : expected key: value, got %q", i+1, content)
+		}
+		key, err := parseYAMLScalar(strings.TrimSpace(content[:colon]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d : %w", i+1, err)
+		}
+		if _, ok := top.table[key]; ok {
+			return nil, fmt.Errorf("line %d : duplicate key %q", i+1, key)
+		}
+		value := strings.TrimSpace(content[colon+1:])
+		if value == "" {
+			pending, pendingTable = key, top.table
+			continue
+		}
+		if top.table[key], err = parseYAMLScalar(value); err != nil {
+			return nil, fmt.Errorf("line %d : %w", i+1, err)
+		}
+	}
+	if pendingTable != nil {
+		pendingTable[pending] = ""
+	}
+	return root, nil
+}
+
+// yamlKeyEnd returns the index of the colon ending the key of a mapping entry, or -1
+func yamlKeyEnd(content string) int {
+	for _, i := range indexesUnquoted(content, ':', true) {
+		if i == len(content)-1 || content[i+1] == ' ' {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseYAMLScalar parses a plain, single-quoted or double-quoted scalar. null and ~ are empty.
+func parseYAMLScalar(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		return strconv.Unquote(value)
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return "", fmt.Errorf("unterminated string %s", value)
+		}
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	case strings.HasPrefix(value, "{"), strings.HasPrefix(value, "["):
+		return "", fmt.Errorf("flow collections are not supported")
+	case strings.HasPrefix(value, "|"), strings.HasPrefix(value, ">"):
+		return "", fmt.Errorf("block scalars are not supported")
+	case value == "null", value == "~":
+		return "", nil
+	default:
+		return value, nil
+	}
+}
+
+// descend returns the table at the path keys below table, creating missing tables
+func descend(table map[string]interface{}, keys []string) (map[string]interface{}, error) {
+	for _, key := range keys {
+		switch next := table[key].(type) {
+		case nil:
+			child := make(map[string]interface{})
+			table[key] = child
+			table = child
+		case map[string]interface{}:
+			table = next
+		default:
+			return nil, fmt.Errorf("key %q is not a table", key)
+		}
+	}
+	return table, nil
+}
+
+// stripComment removes a # comment that is not inside quotes. In YAML, # only starts a comment
+// at the start of the line or after whitespace.
+func stripComment(line string, yaml bool) string {
+	for _, i := range indexesUnquoted(line, '#', yaml) {
+		if !yaml || i == 0 || line[i-1] == ' ' || line[i-1] == '\t' {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// indexUnquoted returns the index of the first c outside quotes, or -1
+func indexUnquoted(s string, c byte) int {
+	if indexes := indexesUnquoted(s, c, false); len(indexes) > 0 {
+		return indexes[0]
+	}
+	return -1
+}
+
+// splitUnquoted splits s on every c outside quotes
+func splitUnquoted(s string, c byte) []string {
+	var parts []string
+	start := 0
+	for _, i := range indexesUnquoted(s, c, false) {
+		parts = append(parts, s[start:i])
+		start = i + 1
+	}
+	return append(parts, s[start:])
+}
+
+// indexesUnquoted returns the indexes of c outside single or double quotes. In YAML, a quote only opens
+// a quoted scalar as its first character, so the apostrophe in a plain scalar such as don't is literal.
+func indexesUnquoted(s string, c byte, yaml bool) []int {
+	var indexes []int
+	var quote byte
+	scalarStart := true
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case (s[i] == '"' || s[i] == '\'') && (!yaml || scalarStart):
+			quote = s[i]
+		case s[i] == c:
+			indexes = append(indexes, i)
+		}
+		if quote == 0 && s[i] != ' ' && s[i] != '\t' {
+			// a YAML value starts after the colon ending its key
+			scalarStart = s[i] == ':' && (i == len(s)-1 || s[i+1] == ' ')
+		}
+	}
+	return indexes
+}
@@ -0,0 +1,117 @@
+package rls
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	data := []byte(`
+# top level comment
+default_profile = "prod" # trailing comment
+
+[profiles.default]
+base_url = "https://rls.example.com/#not-a-comment"
+account_id = 'acct_123'
+headers = { X-Team = "treasury", "X-Quoted, Key" = "a=b" }
+
+[profiles."my prod"]
+api_key = "with \"escaped\" quotes"
+tls.pins = "pin1,pin2"
+enabled = true
+`)
+	got, err := parseTOML(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"default_profile": "prod",
+		"profiles": map[string]interface{}{
+			"default": map[string]interface{}{
+				"base_url":   "https://rls.example.com/#not-a-comment",
+				"account_id": "acct_123",
+				"headers":    map[string]interface{}{"X-Team": "treasury", "X-Quoted, Key": "a=b"},
+			},
+			"my prod": map[string]interface{}{
+				"api_key": `with "escaped" quotes`,
+				"tls":     map[string]interface{}{"pins": "pin1,pin2"},
+				"enabled": "true",
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	for _, doc := range []string{
+		`key`,
+		`key = "unterminated`,
+		`[profiles`,
+		"a = \"x\"\na.b = \"y\"",
+		`list = [1, 2]`,
+	} {
+		if _, err := parseTOML([]byte(doc)); err == nil {
+			t.Errorf("expected an error for %q", doc)
+		}
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	data := []byte(`
+# comment
+default_profile: prod
+profiles:
+  default:
+    base_url: https://rls.example.com/#fragment # comment
+    account_id: "acct_123"
+    api_key: 'it''s secret'
+    headers:
+      X-Team: treasury
+  prod:
+    webhook_secret: ~
+    tls_pins: "a: b"
+    memo: don't # comment
+    owner's: it's 'ours': yes # comment
+    quoted: "it's" # comment
+`)
+	got, err := parseYAML(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"default_profile": "prod",
+		"profiles": map[string]interface{}{
+			"default": map[string]interface{}{
+				"base_url":   "https://rls.example.com/#fragment",
+				"account_id": "acct_123",
+				"api_key":    "it's secret",
+				"headers":    map[string]interface{}{"X-Team": "treasury"},
+			},
+			"prod": map[string]interface{}{
+				"webhook_secret": "",
+				"tls_pins":       "a: b",
+				"memo":           "don't",
+				"owner's":        "it's 'ours': yes",
+				"quoted":         "it's",
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	for _, doc := range []string{
+		"profiles:\n\tdefault: x",
+		"- item",
+		"key: \"unterminated",
+		"a: x\n  b: y",
+	} {
+		if _, err := parseYAML([]byte(doc)); err == nil {
+			t.Errorf("expected an error for %q", doc)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package rls
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 )
@@ -9,9 +10,9 @@ import (
 type Deposit struct {
 	ID        string        `json:"id"`
 	Invoice   Invoice       `json:"deposit_intent"`
-	Amount    int64         `json:"amount"`
+	Amount    Amount        `json:"amount"`
 	Detail    DepositDetail `json:"deposit_details"`
-	State     string        `json:"state"`
+	State     DepositState  `json:"state"`
 	Timestamp int64         `json:"timestamp"`
 }
 
@@ -32,7 +33,15 @@ func (dl *DepositList) Count() int {
 	return len(dl.Deposits)
 }
 
+// GetDeposit returns a single deposit based on its depositID
+//
+// Deprecated: use GetDepositContext
 func (rls *RLSClient) GetDeposit(depositID string) (*Deposit, error) {
+	return rls.GetDepositContext(rls.context(), depositID)
+}
+
+// GetDepositContext returns a single deposit based on its depositID
+func (rls *RLSClient) GetDepositContext(ctx context.Context, depositID string) (*Deposit, error) {
 	url := fmt.Sprintf("%s/accounts/%s/deposits/%s", rls.BaseURL(), rls.AccountID(), depositID)
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
@@ -40,7 +49,7 @@ func (rls *RLSClient) GetDeposit(depositID string) (*Deposit, error) {
 	}
 
 	var deposit Deposit
-	err = rls.sendRequest(req, &deposit)
+	err = rls.sendRequest(ctx, OpGetDeposit, req, &deposit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get deposit : %w", err)
 	}
@@ -48,7 +57,14 @@ func (rls *RLSClient) GetDeposit(depositID string) (*Deposit, error) {
 }
 
 // GetDeposits returns a list of deposits (settled invoices) to RLS
+//
+// Deprecated: use GetDepositsContext
 func (rls *RLSClient) GetDeposits(limit, nextTimestamp int64) (*DepositList, error) {
+	return rls.GetDepositsContext(rls.context(), limit, nextTimestamp)
+}
+
+// GetDepositsContext returns a list of deposits (settled invoices) to RLS
+func (rls *RLSClient) GetDepositsContext(ctx context.Context, limit, nextTimestamp int64) (*DepositList, error) {
 	url := fmt.Sprintf("%s/accounts/%s/deposits", rls.BaseURL(), rls.AccountID())
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
@@ -64,9 +80,13 @@ func (rls *RLSClient) GetDeposits(limit, nextTimestamp int64) (*DepositList, err
 	req.URL.RawQuery = query.Encode()
 
 	var deposits DepositList
-	err = rls.sendRequest(req, &deposits)
+	err = rls.sendRequest(ctx, OpGetDeposits, req, &deposits)
 	if err != nil {
 		return nil, err
 	}
+	rls.observeTimestamps(deposits.NextTimestamp)
+	if len(deposits.Deposits) > 0 {
+		rls.observeTimestamps(deposits.Deposits[0].Timestamp)
+	}
 	return &deposits, nil
 }
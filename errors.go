@@ -0,0 +1,145 @@
+package rls
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors that an *APIError can be matched against using errors.Is
+var (
+	// ErrNotFound is returned when the requested object does not exist
+	ErrNotFound = errors.New("rls: not found")
+	// ErrUnauthorized is returned when the credential is missing, invalid or lacks permission
+	ErrUnauthorized = errors.New("rls: unauthorized")
+	// ErrInsufficientFunds is returned when the account cannot cover a withdrawal
+	ErrInsufficientFunds = errors.New("rls: insufficient funds")
+	// ErrRateLimited is returned when RLS rejects a request with 429 Too Many Requests
+	ErrRateLimited = errors.New("rls: rate limited")
+	// ErrInvalidInvoice is returned when RLS rejects an invoice as malformed, expired or unpayable
+	ErrInvalidInvoice = errors.New("rls: invalid invoice")
+	// ErrUnsupportedCurrency is returned when the account does not hold the requested currency
+	ErrUnsupportedCurrency = errors.New("rls: unsupported currency")
+	// ErrInvalidRequest is returned when the client rejects a request before sending it
+	ErrInvalidRequest = errors.New("rls: invalid request")
+)
+
+// APIError is returned for every non-2xx response from the RLS API
+type APIError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+	// Code is the error code parsed from the response body, if any
+	Code string
+	// Message is the error message parsed from the response body, or the raw body if it was not JSON
+	Message string
+	// Method is the HTTP method of the failed request
+	Method string
+	// Path is the URL path of the failed request
+	Path string
+	// Header contains the response headers
+	Header http.Header
+}
+
+// errorResponse is the JSON body RLS returns alongside an error status
+type errorResponse struct {
+	Code    json.RawMessage `json:"code"`
+	Message string          `json:"message"`
+	Error   string          `json:"error"`
+}
+
+// Error implements error
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if e.Code != "" {
+		msg = fmt.Sprintf("%s: %s", e.Code, msg)
+	}
+	return fmt.Sprintf("%s %s: error code %d: %s", e.Method, e.Path, e.StatusCode, msg)
+}
+
+// Is reports whether the APIError matches one of the package's sentinel errors
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrInsufficientFunds:
+		return e.mentions("insufficient")
+	case ErrInvalidInvoice:
+		return e.mentions("invalid invoice", "invalid_invoice", "invalid destination", "invalid_destination")
+	case ErrUnsupportedCurrency:
+		return e.mentions("unsupported currency", "unsupported_currency")
+	}
+	return false
+}
+
+// mentions reports whether the error code or message contains any of the phrases, ignoring case
+func (e *APIError) mentions(phrases ...string) bool {
+	text := strings.ToLower(e.Code + " " + e.Message)
+	for _, phrase := range phrases {
+		if strings.Contains(text, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// newAPIError builds an APIError from a non-2xx response, consuming its body
+func newAPIError(res *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+	}
+	if res.Request != nil {
+		apiErr.Method = res.Request.Method
+		if res.Request.URL != nil {
+			apiErr.Path = res.Request.URL.Path
+		}
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil || len(body) == 0 {
+		apiErr.Message = "response body is empty"
+		return apiErr
+	}
+
+	var errResp errorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		// body is not json
+		apiErr.Message = strings.TrimSpace(string(body))
+		return apiErr
+	}
+	apiErr.Code = parseErrorCode(errResp.Code)
+	apiErr.Message = errResp.Message
+	if apiErr.Message == "" {
+		apiErr.Message = errResp.Error
+	}
+	if apiErr.Message == "" && apiErr.Code == "" {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	return apiErr
+}
+
+// parseErrorCode accepts both string and numeric error codes
+func parseErrorCode(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var code string
+	if err := json.Unmarshal(raw, &code); err == nil {
+		return code
+	}
+	var num json.Number
+	if err := json.Unmarshal(raw, &num); err == nil {
+		return num.String()
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+package rls
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestClient returns a client without retries talking to handler
+func newTestClient(t *testing.T, handler http.HandlerFunc) *RLSClient {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	client := NewRLSClient(context.Background(), *NewConfig(srv.URL, "key", "acct", "", nil), srv.Client())
+	client.RetryPolicy = nil
+	return client
+}
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		code     string
+		message  string
+		sentinel error
+	}{
+		{"not found", http.StatusNotFound, `{"code":"not_found","message":"no such withdrawal"}`, "not_found", "no such withdrawal", ErrNotFound},
+		{"unauthorized", http.StatusUnauthorized, `{"error":"bad key"}`, "", "bad key", ErrUnauthorized},
+		{"forbidden", http.StatusForbidden, ``, "", "response body is empty", ErrUnauthorized},
+		{"rate limited", http.StatusTooManyRequests, `slow down`, "", "slow down", ErrRateLimited},
+		{"numeric code", http.StatusBadRequest, `{"code":42,"message":"Insufficient funds"}`, "42", "Insufficient funds", ErrInsufficientFunds},
+		{"invalid invoice", http.StatusBadRequest, `{"code":"invalid_invoice"}`, "invalid_invoice", "", ErrInvalidInvoice},
+		{"unsupported currency", http.StatusBadRequest, `{"code":"unsupported_currency","message":"EUR"}`, "unsupported_currency", "EUR", ErrUnsupportedCurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+			_, err := client.GetWithdrawalContext(context.Background(), "wd_1")
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected *APIError, got %v", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Code != tt.code || apiErr.Message != tt.message {
+				t.Errorf("got status %d code %q message %q", apiErr.StatusCode, apiErr.Code, apiErr.Message)
+			}
+			if apiErr.Method != http.MethodGet || apiErr.Path != "/accounts/acct/withdrawals/wd_1" {
+				t.Errorf("got request %s %s", apiErr.Method, apiErr.Path)
+			}
+			if !errors.Is(err, tt.sentinel) {
+				t.Errorf("expected errors.Is(err, %v), got %v", tt.sentinel, err)
+			}
+		})
+	}
+}
+
+func TestAPIErrorDoesNotMatchOtherSentinels(t *testing.T) {
+	err := &APIError{StatusCode: http.StatusNotFound, Message: "gone"}
+	for _, sentinel := range []error{ErrUnauthorized, ErrRateLimited, ErrInsufficientFunds, ErrInvalidInvoice} {
+		if errors.Is(err, sentinel) {
+			t.Errorf("404 must not match %v", sentinel)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package rls
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// FeePolicy chooses the fee limit of a payment from its amount
+type FeePolicy interface {
+	FeeLimit(amount Amount) Amount
+}
+
+// FeePolicyFunc adapts a function to a FeePolicy
+type FeePolicyFunc func(amount Amount) Amount
+
+// FeeLimit calls f(amount)
+func (f FeePolicyFunc) FeeLimit(amount Amount) Amount {
+	return f(amount)
+}
+
+// DefaultFeePolicy is the fee policy used by NewWithdrawal and Pay when none is given
+var DefaultFeePolicy FeePolicy = AbsoluteFee(DefaultFeeLimit)
+
+// AbsoluteFee is a fixed fee limit, whatever the amount
+type AbsoluteFee Amount
+
+// FeeLimit returns the fixed limit, rounded down to the sat as RLS takes fee limits in whole sats
+func (f AbsoluteFee) FeeLimit(Amount) Amount {
+	return floorSats(Amount(f))
+}
+
+func (f AbsoluteFee) String() string {
+	return Amount(f).String()
+}
+
+// MaxFeePPM is the largest proportional fee ParseFeePolicy accepts: 100% of the amount
+const MaxFeePPM = 1000000
+
+// PercentFee limits the fee to a percentage of the amount, e.g. 0.5 for 0.5%, precise to 0.0001%.
+// It must be between 0 and 100.
+type PercentFee float64
+
+// FeeLimit returns the percentage of amount, rounded down to the sat
+func (f PercentFee) FeeLimit(amount Amount) Amount {
+	return PPMFee{PPM: int64(math.Round(float64(f) * 10000))}.FeeLimit(amount)
+}
+
+func (f PercentFee) String() string {
+	return fmt.Sprintf("%g%%", float64(f))
+}
+
+// PPMFee limits the fee to a base fee plus parts per million of the amount, like Lightning channel fees.
+// PPM must be between 0 and 1000000.
+type PPMFee struct {
+	PPM  int64
+	Base Amount
+}
+
+// FeeLimit returns Base plus PPM millionths of amount, rounded down to the sat as RLS takes fee limits in whole sats
+func (f PPMFee) FeeLimit(amount Amount) Amount {
+	limit := new(big.Int).Mul(big.NewInt(int64(amount)), big.NewInt(f.PPM))
+	limit.Quo(limit, big.NewInt(int64(1000000*Satoshi)))
+	return floorSats(f.Base + Sats(limit.Int64()))
+}
+
+func (f PPMFee) String() string {
+	if f.Base == 0 {
+		return fmt.Sprintf("%dppm", f.PPM)
+	}
+	return fmt.Sprintf("%dppm+%s", f.PPM, f.Base.Format(UnitSat))
+}
+
+// floorSats rounds a down to a whole number of sats
+func floorSats(a Amount) Amount {
+	return a - a%Satoshi
+}
+
+// FeeTier applies Policy to amounts up to and including UpTo. An UpTo of 0 means no upper bound.
+type FeeTier struct {
+	UpTo   Amount
+	Policy FeePolicy
+}
+
+// TieredFee applies the policy of the first tier covering the amount, so tiers must be sorted by UpTo
+// with the unbounded tier last. Amounts above every tier use the last one.
+type TieredFee []FeeTier
+
+// FeeLimit returns the limit of the tier covering amount, or 0 if there are no tiers
+func (f TieredFee) FeeLimit(amount Amount) Amount {
+	for _, tier := range f {
+		if tier.UpTo == 0 || amount <= tier.UpTo {
+			return tier.Policy.FeeLimit(amount)
+		}
+	}
+	if len(f) == 0 {
+		return 0
+	}
+	return f[len(f)-1].Policy.FeeLimit(amount)
+}
+
+func (f TieredFee) String() string {
+	tiers := make([]string, len(f))
+	for i, tier := range f {
+		bound := "up to " + tier.UpTo.String()
+		if tier.UpTo == 0 {
+			bound = "any amount"
+			if i > 0 {
+				bound = "above " + f[i-1].UpTo.String()
+			}
+		}
+		tiers[i] = fmt.Sprintf("%s: %v", bound, tier.Policy)
+	}
+	return strings.Join(tiers, ", ")
+}
+
+// ParseFeePolicy parses a fee policy such as "0.5%", "1000ppm", "1000ppm+2sat" or an absolute amount
+// accepted by ParseAmount, e.g. "300" or "300sat". Proportional fees above 100% and absolute or base
+// fees that are not whole sats are rejected.
+func ParseFeePolicy(s string) (FeePolicy, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if percent := strings.TrimSuffix(s, "%"); percent != s {
+		value, ok := new(big.Rat).SetString(strings.TrimSpace(percent))
+		if !ok || value.Sign() < 0 {
+			return nil, fmt.Errorf("invalid fee policy %q", s)
+		}
+		if value.Cmp(big.NewRat(100, 1)) > 0 {
+			return nil, fmt.Errorf("invalid fee policy %q : above 100%%", s)
+		}
+		f, _ := value.Float64()
+		return PercentFee(f), nil
+	}
+	if i := strings.Index(s, "ppm"); i >= 0 {
+		ppm, err := strconv.ParseInt(strings.TrimSpace(s[:i]), 10, 64)
+		if err != nil || ppm < 0 {
+			return nil, fmt.Errorf("invalid fee policy %q", s)
+		}
+		if ppm > MaxFeePPM {
+			return nil, fmt.Errorf("invalid fee policy %q : above %dppm", s, MaxFeePPM)
+		}
+		policy := PPMFee{PPM: ppm}
+		if rest := strings.TrimSpace(s[i+len("ppm"):]); rest != "" {
+			if !strings.HasPrefix(rest, "+") {
+				return nil, fmt.Errorf("invalid fee policy %q", s)
+			}
+			base, err := ParseAmount(rest[1:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid fee policy %q : %w", s, err)
+			}
+			if base%Satoshi != 0 {
+				return nil, fmt.Errorf("invalid fee policy %q : base fee must be whole sats", s)
+			}
+			policy.Base = base
+		}
+		return policy, nil
+	}
+	limit, err := ParseAmount(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fee policy %q : %w", s, err)
+	}
+	if limit%Satoshi != 0 {
+		return nil, fmt.Errorf("invalid fee policy %q : fee limit must be whole sats", s)
+	}
+	return AbsoluteFee(limit), nil
+}
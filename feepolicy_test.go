@@ -0,0 +1,103 @@
+package rls
+
+import (
+	"testing"
+)
+
+func TestFeePolicies(t *testing.T) {
+	tiered := TieredFee{
+		{UpTo: Sats(10000), Policy: AbsoluteFee(Sats(10))},
+		{UpTo: Sats(100000), Policy: PPMFee{PPM: 1000, Base: Sats(1)}},
+		{Policy: PercentFee(0.5)},
+	}
+	tests := []struct {
+		policy FeePolicy
+		amount Amount
+		want   Amount
+	}{
+		{AbsoluteFee(0), Sats(1000), 0},
+		{AbsoluteFee(Sats(300)), Sats(1), Sats(300)},
+		{AbsoluteFee(MSats(1500)), Sats(1000), Sats(1)},
+		{PPMFee{PPM: 1000, Base: MSats(1500)}, Sats(1000), Sats(2)},
+		{PercentFee(0.15), MSats(1000999), Sats(1)},
+		{PercentFee(0.5), Sats(1000), Sats(5)},
+		{PercentFee(0.5), Sats(999), Sats(4)},
+		{PercentFee(100), Sats(1000), Sats(1000)},
+		{PPMFee{PPM: 1000, Base: Sats(2)}, Sats(123456), Sats(125)},
+		{PPMFee{PPM: MaxFeePPM}, Sats(1000000000000), Sats(1000000000000)},
+		{tiered, Sats(10000), Sats(10)},
+		{tiered, Sats(50000), Sats(51)},
+		{tiered, Sats(1000000), Sats(5000)},
+		{TieredFee{{UpTo: Sats(10), Policy: AbsoluteFee(Sats(1))}}, Sats(100), Sats(1)},
+		{TieredFee{}, Sats(100), 0},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.FeeLimit(tt.amount); got != tt.want {
+			t.Errorf("%v of %s: got %s, want %s", tt.policy, tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestParseFeePolicy(t *testing.T) {
+	tests := []struct {
+		in   string
+		want FeePolicy
+	}{
+		{"300", AbsoluteFee(Sats(300))},
+		{"0", AbsoluteFee(0)},
+		{"2000msat", AbsoluteFee(Sats(2))},
+		{" 0.5% ", PercentFee(0.5)},
+		{"100%", PercentFee(100)},
+		{"1000ppm", PPMFee{PPM: 1000}},
+		{"1000PPM + 2sat", PPMFee{PPM: 1000, Base: Sats(2)}},
+		{"1000000ppm", PPMFee{PPM: MaxFeePPM}},
+	}
+	for _, tt := range tests {
+		got, err := ParseFeePolicy(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseFeePolicy(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{
+		"",
+		"-1%",
+		"abc%",
+		"100.01%",
+		"1e20%",
+		"-5ppm",
+		"1000001ppm",
+		"9223372036854775807ppm",
+		"99999999999999999999ppm",
+		"1000ppm 2sat",
+		"1000ppm+x",
+		"1500msat",
+		"1000ppm+1500msat",
+		"fast",
+	} {
+		if policy, err := ParseFeePolicy(in); err == nil {
+			t.Errorf("ParseFeePolicy(%q) = %v, expected an error", in, policy)
+		}
+	}
+}
+
+func TestFeePolicyString(t *testing.T) {
+	tests := []struct {
+		policy FeePolicy
+		want   string
+	}{
+		{AbsoluteFee(Sats(300)), "300 sats"},
+		{PercentFee(0.5), "0.5%"},
+		{PPMFee{PPM: 1000}, "1000ppm"},
+		{PPMFee{PPM: 1000, Base: Sats(2)}, "1000ppm+2 sats"},
+	}
+	for _, tt := range tests {
+		got := tt.policy.(interface{ String() string }).String()
+		if got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+		if parsed, err := ParseFeePolicy(got); err != nil || parsed != tt.policy {
+			t.Errorf("%q parsed as %v, %v", got, parsed, err)
+		}
+	}
+}
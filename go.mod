@@ -2,9 +2,10 @@ module github.com/SachinMeier/rls-client
 
 go 1.17
 
+require github.com/urfave/cli v1.22.10
+
 require (
 	github.com/cpuguy83/go-md2man/v2 v2.0.0-20190314233015-f79a8a8ca69d // indirect
 	github.com/russross/blackfriday/v2 v2.0.1 // indirect
 	github.com/shurcooL/sanitized_anchor_name v1.0.0 // indirect
-	github.com/urfave/cli v1.22.10 // indirect
 )
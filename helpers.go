@@ -1,6 +1,7 @@
 package rls
 
 import (
+	"context"
 	b64 "encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -10,6 +11,9 @@ import (
 
 // setHeaders sets the headers for all HTTP requests
 func (rls *RLSClient) setHeaders(req *http.Request) {
+	if rls.userAgent != "" {
+		req.Header.Set("User-Agent", rls.userAgent)
+	}
 	for k, v := range rls.cfg.ExtraHeaders {
 		req.Header.Set(k, v)
 	}
@@ -18,22 +22,11 @@ func (rls *RLSClient) setHeaders(req *http.Request) {
 	req.Header.Set("Authorization", fmt.Sprintf("basic %s", rls.Credential()))
 }
 
-// handleResponse handles HTTP responses and unmarshals JSON to the appropriate object
+// handleResponse handles HTTP responses and unmarshals JSON to the appropriate object.
+// Non-2xx responses are returned as an *APIError
 func handleResponse(res *http.Response, response interface{}) error {
 	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
-		// var errresp ErrorResponse
-		var errmsg string
-		body, err := io.ReadAll(res.Body)
-		// err is not json
-		if err != nil {
-			// body is empty
-			if len(body) == 0 {
-				errmsg = "response body is empty"
-			}
-		} else {
-			errmsg = string(body)
-		}
-		return fmt.Errorf("error code %d: %s", res.StatusCode, errmsg)
+		return newAPIError(res)
 	}
 
 	if response != nil {
@@ -55,20 +48,63 @@ func createCredential(apiKey string) string {
 	return b64.StdEncoding.EncodeToString([]byte(key))
 }
 
-// sendRequest handles sending HTTP requests
-func (rls *RLSClient) sendRequest(req *http.Request, response interface{}) error {
-	req = req.WithContext(rls.Ctx)
+// sendRequest handles sending HTTP requests for the operation op through the client's middleware.
+// Failed attempts are retried according to rls.RetryPolicy when the request is safe to repeat.
+func (rls *RLSClient) sendRequest(ctx context.Context, op string, req *http.Request, response interface{}) error {
+	if timeout := rls.timeouts.forOperation(op); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	requestID := NewIdempotencyKey()
+	ctx = context.WithValue(ctx, operationContextKey, op)
+	ctx = context.WithValue(ctx, requestIDContextKey, requestID)
+	req = req.WithContext(ctx)
 	rls.setHeaders(req)
+	req.Header.Set(RequestIDHeader, requestID)
 
-	res, err := rls.HTTPClient.Do(req)
-	if err != nil {
-		select {
-		case <-rls.Ctx.Done():
-			return rls.Ctx.Err()
-		default:
-			return err
+	rt := rls.roundTripper()
+	maxAttempts := rls.RetryPolicy.attempts(req)
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return err
+			}
+			req.Body = body
 		}
+
+		res, err := rt.RoundTrip(req.WithContext(context.WithValue(ctx, attemptContextKey, attempt)))
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+			if attempt >= maxAttempts {
+				return err
+			}
+			if err := sleep(ctx, rls.RetryPolicy.Backoff(attempt)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if attempt < maxAttempts && rls.RetryPolicy.isRetryableStatus(res.StatusCode) {
+			delay, ok := retryAfter(res)
+			if !ok {
+				delay = rls.RetryPolicy.Backoff(attempt)
+			}
+			// drain the body so the connection can be reused
+			_, _ = io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+			if err := sleep(ctx, delay); err != nil {
+				return err
+			}
+			continue
+		}
+
+		defer res.Body.Close()
+		return handleResponse(res, response)
 	}
-	defer res.Body.Close()
-	return handleResponse(res, response)
 }
@@ -0,0 +1,99 @@
+package rls
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// historyRange returns the iterator options covering [from, to), with timestamps in the unit RLS uses
+func (rls *RLSClient) historyRange(ctx context.Context, from, to time.Time, probe func(ctx context.Context) error) (IteratorOptions, error) {
+	if !from.Before(to) {
+		return IteratorOptions{}, fmt.Errorf("invalid time range : from %s is not before to %s", from, to)
+	}
+	cursor, err := rls.cursorFromTime(ctx, to, probe)
+	if err != nil {
+		return IteratorOptions{}, err
+	}
+	stopBefore, err := rls.cursorFromTime(ctx, from, probe)
+	if err != nil {
+		return IteratorOptions{}, err
+	}
+	return IteratorOptions{
+		Cursor:     cursor,
+		StopBefore: stopBefore,
+	}, nil
+}
+
+// inRange returns true if the RLS timestamp ts falls in [from, to)
+func inRange(ts int64, from, to time.Time) bool {
+	t := TimeFromTimestamp(ts)
+	return !t.Before(from) && t.Before(to)
+}
+
+// DepositsBetween returns the deposits made in [from, to), oldest first. It starts paging at to
+// and stops as soon as deposits fall before from.
+func (rls *RLSClient) DepositsBetween(ctx context.Context, from, to time.Time) ([]Deposit, error) {
+	opts, err := rls.historyRange(ctx, from, to, rls.probeDeposits)
+	if err != nil {
+		return nil, err
+	}
+
+	var deposits []Deposit
+	it := rls.IterateDeposits(opts)
+	for it.Next(ctx) {
+		if deposit := it.Value(); inRange(deposit.Timestamp, from, to) {
+			deposits = append(deposits, deposit)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list deposits between %s and %s : %w", from, to, err)
+	}
+	sort.SliceStable(deposits, func(i, j int) bool { return deposits[i].Time().Before(deposits[j].Time()) })
+	return deposits, nil
+}
+
+// WithdrawalsBetween returns the withdrawals made in [from, to), oldest first. It starts paging at to
+// and stops as soon as withdrawals fall before from.
+func (rls *RLSClient) WithdrawalsBetween(ctx context.Context, from, to time.Time) ([]Withdrawal, error) {
+	opts, err := rls.historyRange(ctx, from, to, rls.probeWithdrawals)
+	if err != nil {
+		return nil, err
+	}
+
+	var withdrawals []Withdrawal
+	it := rls.IterateWithdrawals(opts)
+	for it.Next(ctx) {
+		if withdrawal := it.Value(); inRange(withdrawal.Timestamp, from, to) {
+			withdrawals = append(withdrawals, withdrawal)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list withdrawals between %s and %s : %w", from, to, err)
+	}
+	sort.SliceStable(withdrawals, func(i, j int) bool { return withdrawals[i].Time().Before(withdrawals[j].Time()) })
+	return withdrawals, nil
+}
+
+// InvoicesBetween returns the deposit invoices created in [from, to), oldest first. It starts paging at to
+// and stops as soon as invoices fall before from.
+func (rls *RLSClient) InvoicesBetween(ctx context.Context, from, to time.Time) ([]Invoice, error) {
+	opts, err := rls.historyRange(ctx, from, to, rls.probeInvoices)
+	if err != nil {
+		return nil, err
+	}
+
+	var invoices []Invoice
+	it := rls.IterateInvoices(opts)
+	for it.Next(ctx) {
+		if invoice := it.Value(); inRange(invoice.Timestamp, from, to) {
+			invoices = append(invoices, invoice)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list invoices between %s and %s : %w", from, to, err)
+	}
+	sort.SliceStable(invoices, func(i, j int) bool { return invoices[i].Time().Before(invoices[j].Time()) })
+	return invoices, nil
+}
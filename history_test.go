@@ -0,0 +1,35 @@
+package rls
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithdrawalsBetween(t *testing.T) {
+	var limits []int64
+	client := newTestClient(t, pagedWithdrawals([]int64{1700000050, 1700000040, 1700000030, 1700000020, 1700000010}, &limits))
+	from, to := time.Unix(1700000020, 0), time.Unix(1700000050, 0)
+	withdrawals, err := client.WithdrawalsBetween(context.Background(), from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int64
+	for _, wd := range withdrawals {
+		got = append(got, wd.Timestamp)
+	}
+	if want := []int64{1700000020, 1700000030, 1700000040}; !equalInt64s(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestHistoryInvalidRange(t *testing.T) {
+	client := newTestClient(t, nil)
+	now := time.Now()
+	if _, err := client.DepositsBetween(context.Background(), now, now); err == nil {
+		t.Error("expected an error for an empty range")
+	}
+	if _, err := client.InvoicesBetween(context.Background(), now, now.Add(-time.Hour)); err == nil {
+		t.Error("expected an error for a reversed range")
+	}
+}
@@ -0,0 +1,106 @@
+package rls
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// DefaultRecoveryAttempts is the number of times the recovery helpers submit a request
+// before giving up on an ambiguous failure
+const DefaultRecoveryAttempts = 5
+
+// NewIdempotencyKey returns a random (version 4) UUID to be used as an idempotency key
+func NewIdempotencyKey() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("rls: failed to generate idempotency key : %v", err))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// IsAmbiguous reports whether err leaves it unknown if RLS executed the request, e.g. a timeout,
+// a dropped connection, a 5xx response or an unreadable 2xx response. Requests that failed
+// ambiguously can be safely resubmitted with the same idempotency key. Requests rejected before
+// being sent, matching ErrInvalidRequest or ErrUnsupportedCurrency, are not ambiguous.
+func IsAmbiguous(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrInvalidRequest) || errors.Is(err, ErrUnsupportedCurrency) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= http.StatusInternalServerError ||
+			apiErr.StatusCode == http.StatusRequestTimeout ||
+			apiErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// NewWithdrawalWithRecovery submits withdrawal and, if the outcome is ambiguous, re-issues the same
+// request with the same idempotency key until the original result is recovered, a definitive error is
+// returned, maxAttempts submissions have been made or ctx is done.
+func (rls *RLSClient) NewWithdrawalWithRecovery(ctx context.Context, withdrawal *Withdrawal, maxAttempts int) (*Withdrawal, error) {
+	if withdrawal.IdempotencyKey == "" {
+		withdrawal.IdempotencyKey = NewIdempotencyKey()
+	}
+
+	var wd *Withdrawal
+	err := rls.recoverAmbiguous(ctx, maxAttempts, func() error {
+		var err error
+		wd, err = rls.NewWithdrawalContext(ctx, withdrawal)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover withdrawal %s : %w", withdrawal.IdempotencyKey, err)
+	}
+	return wd, nil
+}
+
+// SubmitInvoiceRequestWithRecovery submits invoiceReq and, if the outcome is ambiguous, re-issues the
+// same request with the same idempotency key, in the same way as NewWithdrawalWithRecovery
+func (rls *RLSClient) SubmitInvoiceRequestWithRecovery(ctx context.Context, invoiceReq *InvoiceRequest, maxAttempts int) (*Invoice, error) {
+	if invoiceReq.IdempotencyKey == "" {
+		invoiceReq.IdempotencyKey = NewIdempotencyKey()
+	}
+
+	var invoice *Invoice
+	err := rls.recoverAmbiguous(ctx, maxAttempts, func() error {
+		var err error
+		invoice, err = rls.SubmitInvoiceRequest(ctx, invoiceReq)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover invoice %s : %w", invoiceReq.IdempotencyKey, err)
+	}
+	return invoice, nil
+}
+
+// recoverAmbiguous calls submit until it succeeds, fails unambiguously or runs out of attempts
+func (rls *RLSClient) recoverAmbiguous(ctx context.Context, maxAttempts int, submit func() error) error {
+	if maxAttempts < 1 {
+		maxAttempts = DefaultRecoveryAttempts
+	}
+	policy := rls.RetryPolicy
+	if policy == nil {
+		policy = DefaultRetryPolicy()
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err = submit()
+		if !IsAmbiguous(err) || attempt == maxAttempts {
+			return err
+		}
+		if sleepErr := sleep(ctx, policy.Backoff(attempt)); sleepErr != nil {
+			return err
+		}
+	}
+	return err
+}
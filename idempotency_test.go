@@ -0,0 +1,125 @@
+package rls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewIdempotencyKey(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		key := NewIdempotencyKey()
+		if !uuidPattern.MatchString(key) {
+			t.Fatalf("%q is not a version 4 UUID", key)
+		}
+		if seen[key] {
+			t.Fatalf("duplicate key %q", key)
+		}
+		seen[key] = true
+	}
+}
+
+func TestIsAmbiguous(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{context.Canceled, false},
+		{context.DeadlineExceeded, true},
+		{errors.New("connection reset"), true},
+		{checkWholeSats("amount", MSats(1500)), false},
+		{fmt.Errorf("failed to create withdrawal : %w", ErrUnsupportedCurrency), false},
+		{&APIError{StatusCode: http.StatusBadRequest}, false},
+		{&APIError{StatusCode: http.StatusNotFound}, false},
+		{&APIError{StatusCode: http.StatusRequestTimeout}, true},
+		{&APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{&APIError{StatusCode: http.StatusBadGateway}, true},
+	}
+	for _, tt := range tests {
+		if got := IsAmbiguous(tt.err); got != tt.want {
+			t.Errorf("IsAmbiguous(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestNewWithdrawalWithRecoveryReusesKey(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		if len(keys) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"id":"wd_1","state":"PENDING"}`))
+	})
+	client.RetryPolicy = fastRetryPolicy()
+	client.RetryPolicy.MaxAttempts = 1
+
+	withdrawal := NewWithdrawal(1000, "lnbc1")
+	wd, err := client.NewWithdrawalWithRecovery(context.Background(), withdrawal, 3)
+	if err != nil || wd.ID != "wd_1" {
+		t.Fatalf("got %v, %v", wd, err)
+	}
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Fatalf("expected the same key on both submissions, got %q", keys)
+	}
+	if withdrawal.IdempotencyKey != keys[0] || wd.IdempotencyKey != keys[0] {
+		t.Errorf("key not stored on the withdrawals: %q, %q", withdrawal.IdempotencyKey, wd.IdempotencyKey)
+	}
+}
+
+func TestNewWithdrawalWithRecoveryStopsOnDefinitiveError(t *testing.T) {
+	calls := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":"insufficient_funds"}`))
+	})
+	client.RetryPolicy = fastRetryPolicy()
+	_, err := client.NewWithdrawalWithRecovery(context.Background(), NewWithdrawal(1000, "lnbc1"), 3)
+	if !errors.Is(err, ErrInsufficientFunds) || calls != 1 {
+		t.Fatalf("got %v after %d calls", err, calls)
+	}
+}
+
+func TestRecoveryStopsOnInvalidRequest(t *testing.T) {
+	calls := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"id":"acct","balance":1000,"available_balance":1000}`))
+	})
+	// a retry would wait far longer than the test allows
+	client.RetryPolicy = &RetryPolicy{MaxAttempts: 1, InitialBackoff: time.Minute, MaxBackoff: time.Minute}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	subSat := NewWithdrawalFromAmount(MSats(1500), "lnbc1", Sats(10))
+	if _, err := client.NewWithdrawalWithRecovery(ctx, subSat, DefaultRecoveryAttempts); !errors.Is(err, ErrInvalidRequest) || calls != 0 {
+		t.Errorf("got %v after %d calls", err, calls)
+	}
+	invoiceReq := NewInvoiceRequestFromAmount(MSats(1500), "label", LN)
+	if _, err := client.SubmitInvoiceRequestWithRecovery(ctx, invoiceReq, DefaultRecoveryAttempts); !errors.Is(err, ErrInvalidRequest) || calls != 0 {
+		t.Errorf("got %v after %d calls", err, calls)
+	}
+
+	// the currency is checked against the account once, and the withdrawal is never submitted
+	unsupported := NewWithdrawalWithCurrency("USD", 100, "lnbc1", 0)
+	if _, err := client.NewWithdrawalWithRecovery(ctx, unsupported, DefaultRecoveryAttempts); !errors.Is(err, ErrUnsupportedCurrency) || calls != 1 {
+		t.Errorf("got %v after %d calls", err, calls)
+	}
+	if ctx.Err() != nil {
+		t.Error("invalid requests were retried")
+	}
+}
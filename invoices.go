@@ -2,18 +2,32 @@ package rls
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 )
 
+// InvoiceRequest contains the parameters of a new deposit invoice
 type InvoiceRequest struct {
-	Amount  int64  `json:"amount"`
+	// Amount of the deposit. For a currency other than BTC, Amount.Sats() is the amount in its base unit
+	Amount  Amount `json:"amount"`
 	Label   string `json:"label"`
 	Network string `json:"network"`
+	// Currency of the deposit. Empty means BTC
+	Currency string `json:"currency,omitempty"`
+	// IdempotencyKey is sent in the Idempotency-Key header when the request is submitted.
+	// It is generated by SubmitInvoiceRequest if empty.
+	IdempotencyKey string `json:"-"`
 }
 
+// NewInvoiceRequest returns an InvoiceRequest for amount sats to be passed to SubmitInvoiceRequest
 func NewInvoiceRequest(amount int64, label string, network string) *InvoiceRequest {
+	return NewInvoiceRequestFromAmount(Sats(amount), label, network)
+}
+
+// NewInvoiceRequestFromAmount returns an InvoiceRequest for amount to be passed to SubmitInvoiceRequest
+func NewInvoiceRequestFromAmount(amount Amount, label string, network string) *InvoiceRequest {
 	return &InvoiceRequest{
 		Amount:  amount,
 		Label:   label,
@@ -21,6 +35,14 @@ func NewInvoiceRequest(amount int64, label string, network string) *InvoiceReque
 	}
 }
 
+// NewInvoiceRequestWithCurrency returns an InvoiceRequest for a deposit in currency to be passed to SubmitInvoiceRequest.
+// amount is in the currency's base unit, sats for BTC.
+func NewInvoiceRequestWithCurrency(currency string, amount int64, label string, network string) *InvoiceRequest {
+	invReq := NewInvoiceRequest(amount, label, network)
+	invReq.Currency = currency
+	return invReq
+}
+
 // Invoice contains the response from creating or querying
 // a Deposit Invoice.
 type Invoice struct {
@@ -28,6 +50,8 @@ type Invoice struct {
 	Invoice   string `json:"destination"`
 	Network   string `json:"network"`
 	Timestamp int64  `json:"timestamp,omitempty"`
+	// IdempotencyKey is the key the invoice was created with, if it was created by this client
+	IdempotencyKey string `json:"-"`
 }
 
 // InvoiceList contains the a page of responses from GetDeposits
@@ -41,30 +65,67 @@ func (dil *InvoiceList) Count() int {
 	return len(dil.Invoices)
 }
 
-// NewInvoice creates an invoice to enable deposits to RLS
+// NewInvoice creates an invoice of amount sats to enable deposits to RLS
+//
+// Deprecated: use NewInvoiceContext
 func (rls *RLSClient) NewInvoice(amount int64, label string, network string) (*Invoice, error) {
-	invoiceReq := NewInvoiceRequest(amount, label, network)
+	return rls.NewInvoiceContext(rls.context(), Sats(amount), label, network)
+}
+
+// NewInvoiceContext creates an invoice to enable deposits to RLS
+func (rls *RLSClient) NewInvoiceContext(ctx context.Context, amount Amount, label string, network string) (*Invoice, error) {
+	return rls.SubmitInvoiceRequest(ctx, NewInvoiceRequestFromAmount(amount, label, network))
+}
+
+// NewInvoiceWithCurrency creates an invoice for a deposit of amount in currency, which must be held by the account.
+// amount is in the currency's base unit, sats for BTC.
+func (rls *RLSClient) NewInvoiceWithCurrency(ctx context.Context, currency string, amount int64, label string, network string) (*Invoice, error) {
+	return rls.SubmitInvoiceRequest(ctx, NewInvoiceRequestWithCurrency(currency, amount, label, network))
+}
+
+// SubmitInvoiceRequest creates an invoice from invoiceReq. If invoiceReq.IdempotencyKey is empty,
+// a new key is generated and stored on invoiceReq. A currency other than BTC is validated against
+// the account's currencies first, and an amount that is not whole sats is rejected before sending.
+func (rls *RLSClient) SubmitInvoiceRequest(ctx context.Context, invoiceReq *InvoiceRequest) (*Invoice, error) {
+	if err := checkWholeSats("amount", invoiceReq.Amount); err != nil {
+		return nil, fmt.Errorf("failed to create invoice : %w", err)
+	}
+	if err := rls.ValidateCurrency(ctx, invoiceReq.Currency); err != nil {
+		return nil, err
+	}
+	if invoiceReq.IdempotencyKey == "" {
+		invoiceReq.IdempotencyKey = NewIdempotencyKey()
+	}
 
 	body, err := json.Marshal(invoiceReq)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to create invoice : %w : %v", ErrInvalidRequest, err)
 	}
 	url := fmt.Sprintf("%s/accounts/%s/deposit_intents", rls.BaseURL(), rls.AccountID())
 	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(body))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to create invoice : %w : %v", ErrInvalidRequest, err)
 	}
+	req.Header.Set(IdempotencyKeyHeader, invoiceReq.IdempotencyKey)
 
 	var invoice Invoice
-	err = rls.sendRequest(req, &invoice)
+	err = rls.sendRequest(ctx, OpNewInvoice, req, &invoice)
 	if err != nil {
 		return nil, err
 	}
+	invoice.IdempotencyKey = invoiceReq.IdempotencyKey
 	return &invoice, nil
 }
 
 // GetInvoice gets an existing deposit intent from RLS using its ID
+//
+// Deprecated: use GetInvoiceContext
 func (rls *RLSClient) GetInvoice(invoiceID string) (*Invoice, error) {
+	return rls.GetInvoiceContext(rls.context(), invoiceID)
+}
+
+// GetInvoiceContext gets an existing deposit intent from RLS using its ID
+func (rls *RLSClient) GetInvoiceContext(ctx context.Context, invoiceID string) (*Invoice, error) {
 	url := fmt.Sprintf("%s/accounts/%s/deposit_intents/%s", rls.BaseURL(), rls.AccountID(), invoiceID)
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
@@ -72,7 +133,7 @@ func (rls *RLSClient) GetInvoice(invoiceID string) (*Invoice, error) {
 	}
 
 	var invoice Invoice
-	err = rls.sendRequest(req, &invoice)
+	err = rls.sendRequest(ctx, OpGetInvoice, req, &invoice)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get invoice : %w", err)
 	}
@@ -80,7 +141,14 @@ func (rls *RLSClient) GetInvoice(invoiceID string) (*Invoice, error) {
 }
 
 // GetInvoices queries a list of invoices generated by RLS
+//
+// Deprecated: use GetInvoicesContext
 func (rls *RLSClient) GetInvoices(limit int64, nextTimestamp int64) (*InvoiceList, error) {
+	return rls.GetInvoicesContext(rls.context(), limit, nextTimestamp)
+}
+
+// GetInvoicesContext queries a list of invoices generated by RLS
+func (rls *RLSClient) GetInvoicesContext(ctx context.Context, limit int64, nextTimestamp int64) (*InvoiceList, error) {
 	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/accounts/%s/deposit_intents", rls.BaseURL(), rls.AccountID()), nil)
 	if err != nil {
 		return nil, err
@@ -95,14 +163,25 @@ func (rls *RLSClient) GetInvoices(limit int64, nextTimestamp int64) (*InvoiceLis
 	req.URL.RawQuery = query.Encode()
 
 	var invoices InvoiceList
-	err = rls.sendRequest(req, &invoices)
+	err = rls.sendRequest(ctx, OpGetInvoices, req, &invoices)
 	if err != nil {
 		return nil, err
 	}
+	rls.observeTimestamps(invoices.NextTimestamp)
+	if len(invoices.Invoices) > 0 {
+		rls.observeTimestamps(invoices.Invoices[0].Timestamp)
+	}
 	return &invoices, nil
 }
 
 // GetNextPageInvoices takes a InvoiceList and returns the next limit Invoices
+//
+// Deprecated: use GetNextPageInvoicesContext
 func (rls *RLSClient) GetNextPageInvoices(limit int64, prevList *InvoiceList) (*InvoiceList, error) {
-	return rls.GetInvoices(limit, prevList.NextTimestamp)
+	return rls.GetNextPageInvoicesContext(rls.context(), limit, prevList)
+}
+
+// GetNextPageInvoicesContext takes a InvoiceList and returns the next limit Invoices
+func (rls *RLSClient) GetNextPageInvoicesContext(ctx context.Context, limit int64, prevList *InvoiceList) (*InvoiceList, error) {
+	return rls.GetInvoicesContext(ctx, limit, prevList.NextTimestamp)
 }
@@ -0,0 +1,235 @@
+package rls
+
+import "context"
+
+// DefaultPageSize is the page size used by iterators when IteratorOptions.PageSize is not set
+const DefaultPageSize int64 = 25
+
+// IteratorOptions configures the pagination of DepositIterator, WithdrawalIterator and InvoiceIterator
+type IteratorOptions struct {
+	// PageSize is the limit passed to each list call. Defaults to DefaultPageSize
+	PageSize int64
+	// MaxItems caps the number of items returned. 0 returns every item
+	MaxItems int
+	// Cursor is the next_timestamp of the first page. 0 starts from the most recent item
+	Cursor int64
+	// StopBefore ends the iteration at the first item with a timestamp before it. 0 disables it.
+	// Item timestamps in milliseconds are compared correctly against a StopBefore in seconds.
+	StopBefore int64
+}
+
+// pager holds the pagination state shared by the iterators
+type pager struct {
+	opts     IteratorOptions
+	cursor   int64
+	lastPage bool
+	done     bool
+	count    int
+	err      error
+}
+
+func newPager(opts IteratorOptions) pager {
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultPageSize
+	}
+	return pager{opts: opts, cursor: opts.Cursor}
+}
+
+// canFetch returns true if another page should be fetched
+func (p *pager) canFetch() bool {
+	if p.opts.MaxItems > 0 && p.count >= p.opts.MaxItems {
+		return false
+	}
+	return !p.done && !p.lastPage && p.err == nil
+}
+
+// pageSize returns the limit of the next list call, trimmed to the items left under MaxItems
+func (p *pager) pageSize() int64 {
+	if p.opts.MaxItems > 0 {
+		if left := int64(p.opts.MaxItems - p.count); left < p.opts.PageSize {
+			return left
+		}
+	}
+	return p.opts.PageSize
+}
+
+// advance moves the cursor after a page of n items with the given next_timestamp was fetched
+func (p *pager) advance(nextTimestamp int64, n int) {
+	if nextTimestamp == 0 || n == 0 || nextTimestamp == p.cursor {
+		p.lastPage = true
+	}
+	p.cursor = nextTimestamp
+}
+
+// accept returns true if an item with the given timestamp should be returned, ending the iteration otherwise
+func (p *pager) accept(timestamp int64) bool {
+	if p.opts.MaxItems > 0 && p.count >= p.opts.MaxItems {
+		p.done = true
+		return false
+	}
+	if p.opts.StopBefore > 0 && TimeFromTimestamp(timestamp).Before(TimeFromTimestamp(p.opts.StopBefore)) {
+		p.done = true
+		return false
+	}
+	p.count++
+	return true
+}
+
+// fail ends the iteration with err
+func (p *pager) fail(err error) bool {
+	p.err = err
+	p.done = true
+	return false
+}
+
+// Err returns the error that ended the iteration, if any
+func (p *pager) Err() error {
+	return p.err
+}
+
+// DepositIterator iterates over deposits, most recent first, following next_timestamp transparently
+type DepositIterator struct {
+	pager
+	client *RLSClient
+	page   []Deposit
+	value  Deposit
+}
+
+// IterateDeposits returns a DepositIterator over the account's deposits
+func (rls *RLSClient) IterateDeposits(opts IteratorOptions) *DepositIterator {
+	return &DepositIterator{pager: newPager(opts), client: rls}
+}
+
+// Next advances to the next deposit, fetching pages as needed. It returns false when the
+// iteration is over or failed, in which case Err returns the error.
+func (it *DepositIterator) Next(ctx context.Context) bool {
+	for len(it.page) == 0 {
+		if !it.canFetch() {
+			it.done = true
+			return false
+		}
+		list, err := it.client.GetDepositsContext(ctx, it.pageSize(), it.cursor)
+		if err != nil {
+			return it.fail(err)
+		}
+		it.page = list.Deposits
+		it.advance(list.NextTimestamp, len(list.Deposits))
+	}
+	if it.done || !it.accept(it.page[0].Timestamp) {
+		return false
+	}
+	it.value, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Value returns the current deposit
+func (it *DepositIterator) Value() Deposit {
+	return it.value
+}
+
+// ListAll collects the remaining deposits into a slice
+func (it *DepositIterator) ListAll(ctx context.Context) ([]Deposit, error) {
+	var deposits []Deposit
+	for it.Next(ctx) {
+		deposits = append(deposits, it.Value())
+	}
+	return deposits, it.Err()
+}
+
+// WithdrawalIterator iterates over withdrawals, most recent first, following next_timestamp transparently
+type WithdrawalIterator struct {
+	pager
+	client *RLSClient
+	page   []Withdrawal
+	value  Withdrawal
+}
+
+// IterateWithdrawals returns a WithdrawalIterator over the account's withdrawals
+func (rls *RLSClient) IterateWithdrawals(opts IteratorOptions) *WithdrawalIterator {
+	return &WithdrawalIterator{pager: newPager(opts), client: rls}
+}
+
+// Next advances to the next withdrawal, fetching pages as needed. It returns false when the
+// iteration is over or failed, in which case Err returns the error.
+func (it *WithdrawalIterator) Next(ctx context.Context) bool {
+	for len(it.page) == 0 {
+		if !it.canFetch() {
+			it.done = true
+			return false
+		}
+		list, err := it.client.ListWithdrawalsContext(ctx, it.pageSize(), it.cursor)
+		if err != nil {
+			return it.fail(err)
+		}
+		it.page = list.Withdrawals
+		it.advance(list.NextTimestamp, len(list.Withdrawals))
+	}
+	if it.done || !it.accept(it.page[0].Timestamp) {
+		return false
+	}
+	it.value, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Value returns the current withdrawal
+func (it *WithdrawalIterator) Value() Withdrawal {
+	return it.value
+}
+
+// ListAll collects the remaining withdrawals into a slice
+func (it *WithdrawalIterator) ListAll(ctx context.Context) ([]Withdrawal, error) {
+	var withdrawals []Withdrawal
+	for it.Next(ctx) {
+		withdrawals = append(withdrawals, it.Value())
+	}
+	return withdrawals, it.Err()
+}
+
+// InvoiceIterator iterates over deposit invoices, most recent first, following next_timestamp transparently
+type InvoiceIterator struct {
+	pager
+	client *RLSClient
+	page   []Invoice
+	value  Invoice
+}
+
+// IterateInvoices returns an InvoiceIterator over the account's deposit invoices
+func (rls *RLSClient) IterateInvoices(opts IteratorOptions) *InvoiceIterator {
+	return &InvoiceIterator{pager: newPager(opts), client: rls}
+}
+
+// Next advances to the next invoice, fetching pages as needed. It returns false when the
+// iteration is over or failed, in which case Err returns the error.
+func (it *InvoiceIterator) Next(ctx context.Context) bool {
+	for len(it.page) == 0 {
+		if !it.canFetch() {
+			it.done = true
+			return false
+		}
+		list, err := it.client.GetInvoicesContext(ctx, it.pageSize(), it.cursor)
+		if err != nil {
+			return it.fail(err)
+		}
+		it.page = list.Invoices
+		it.advance(list.NextTimestamp, len(list.Invoices))
+	}
+	if it.done || !it.accept(it.page[0].Timestamp) {
+		return false
+	}
+	it.value, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Value returns the current invoice
+func (it *InvoiceIterator) Value() Invoice {
+	return it.value
+}
+
+// ListAll collects the remaining invoices into a slice
+func (it *InvoiceIterator) ListAll(ctx context.Context) ([]Invoice, error) {
+	var invoices []Invoice
+	for it.Next(ctx) {
+		invoices = append(invoices, it.Value())
+	}
+	return invoices, it.Err()
+}
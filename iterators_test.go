@@ -0,0 +1,124 @@
+package rls
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// pagedWithdrawals serves withdrawals with the given timestamps, newest first, paginated like the RLS API.
+// It records the limit of each list call in limits.
+func pagedWithdrawals(timestamps []int64, limits *[]int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
+		cursor, _ := strconv.ParseInt(r.URL.Query().Get("next_timestamp"), 10, 64)
+		*limits = append(*limits, limit)
+		list := WithdrawalList{Withdrawals: []Withdrawal{}}
+		for _, ts := range timestamps {
+			if cursor != 0 && ts > cursor {
+				continue
+			}
+			if int64(len(list.Withdrawals)) == limit {
+				list.NextTimestamp = ts
+				break
+			}
+			list.Withdrawals = append(list.Withdrawals, Withdrawal{ID: "wd_" + strconv.FormatInt(ts, 10), Timestamp: ts})
+		}
+		json.NewEncoder(w).Encode(list)
+	}
+}
+
+func TestWithdrawalIterator(t *testing.T) {
+	var limits []int64
+	client := newTestClient(t, pagedWithdrawals([]int64{50, 40, 30, 20, 10}, &limits))
+	withdrawals, err := client.IterateWithdrawals(IteratorOptions{PageSize: 2}).ListAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(withdrawals) != 5 || withdrawals[0].Timestamp != 50 || withdrawals[4].Timestamp != 10 {
+		t.Fatalf("got %+v", withdrawals)
+	}
+	if len(limits) != 3 {
+		t.Errorf("expected 3 pages, got %d", len(limits))
+	}
+}
+
+func TestIteratorOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   IteratorOptions
+		want   []int64
+		limits []int64
+	}{
+		{"max items trims the last page", IteratorOptions{PageSize: 2, MaxItems: 3}, []int64{50, 40, 30}, []int64{2, 1}},
+		{"cursor", IteratorOptions{PageSize: 10, Cursor: 30}, []int64{30, 20, 10}, []int64{10}},
+		{"stop before", IteratorOptions{PageSize: 2, StopBefore: 25}, []int64{50, 40, 30}, []int64{2, 2}},
+	}
+	for _, tt := range tests {
+		var limits []int64
+		client := newTestClient(t, pagedWithdrawals([]int64{50, 40, 30, 20, 10}, &limits))
+		it := client.IterateWithdrawals(tt.opts)
+		var got []int64
+		for it.Next(context.Background()) {
+			got = append(got, it.Value().Timestamp)
+		}
+		if it.Err() != nil {
+			t.Fatalf("%s: %v", tt.name, it.Err())
+		}
+		if !equalInt64s(got, tt.want) || !equalInt64s(limits, tt.limits) {
+			t.Errorf("%s: got %v with limits %v, want %v with limits %v", tt.name, got, limits, tt.want, tt.limits)
+		}
+	}
+}
+
+func TestIteratorStopBeforeMilliseconds(t *testing.T) {
+	var limits []int64
+	client := newTestClient(t, pagedWithdrawals([]int64{1700000050000, 1700000030000, 1700000010000}, &limits))
+	withdrawals, err := client.IterateWithdrawals(IteratorOptions{StopBefore: 1700000020}).ListAll(context.Background())
+	if err != nil || len(withdrawals) != 2 {
+		t.Fatalf("got %+v, %v", withdrawals, err)
+	}
+}
+
+func TestIteratorStopsOnError(t *testing.T) {
+	calls := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls > 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"deposits":[{"id":"dep_2","timestamp":2}],"next_timestamp":1}`))
+	})
+	deposits, err := client.IterateDeposits(IteratorOptions{PageSize: 1}).ListAll(context.Background())
+	if !errors.Is(err, ErrUnauthorized) || len(deposits) != 1 {
+		t.Fatalf("got %v, %v", deposits, err)
+	}
+}
+
+func TestIteratorStopsOnRepeatedCursor(t *testing.T) {
+	calls := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"deposit_intents":[{"id":"di_1","timestamp":5}],"next_timestamp":5}`))
+	})
+	invoices, err := client.IterateInvoices(IteratorOptions{Cursor: 5}).ListAll(context.Background())
+	if err != nil || len(invoices) != 1 || calls != 1 {
+		t.Fatalf("got %v, %v after %d calls", invoices, err, calls)
+	}
+}
+
+func equalInt64s(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,270 @@
+package rls
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const (
+	// keystoreVersion is the version of the keystore file format
+	keystoreVersion = 1
+	// keystoreKDF names the key derivation used for entries
+	keystoreKDF = "pbkdf2-sha256"
+	// DefaultKeystoreIterations is the number of PBKDF2 iterations used to derive entry keys
+	DefaultKeystoreIterations = 310000
+	// maxKeystoreIterations bounds the iterations read from a keystore file, so a corrupted or
+	// tampered entry cannot stall key derivation for hours
+	maxKeystoreIterations = 10 * DefaultKeystoreIterations
+	keystoreSaltSize      = 16
+	keystoreKeySize       = 32
+)
+
+var (
+	// ErrSecretNotFound is returned when a keystore has no entry with the requested name
+	ErrSecretNotFound = errors.New("rls: secret not found in keystore")
+	// ErrWrongPassphrase is returned when a keystore entry cannot be decrypted with the passphrase
+	ErrWrongPassphrase = errors.New("rls: wrong keystore passphrase")
+)
+
+// Keystore is a local file of secrets, each encrypted with AES-256-GCM under a key derived
+// from a passphrase with PBKDF2-SHA256
+type Keystore struct {
+	// Path is the keystore file
+	Path string
+	// Iterations is the number of PBKDF2 iterations used by Put, at most 10 times DefaultKeystoreIterations.
+	// Defaults to DefaultKeystoreIterations
+	Iterations int
+
+	mu sync.Mutex
+}
+
+type keystoreFile struct {
+	Version int                       `json:"version"`
+	Entries map[string]*keystoreEntry `json:"entries"`
+}
+
+type keystoreEntry struct {
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// DefaultKeystorePath returns the default keystore file, e.g. ~/.config/rls/keystore.json
+func DefaultKeystorePath() (string, error) {
+	dir, err := DefaultConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "keystore.json"), nil
+}
+
+// OpenKeystore returns the Keystore at path. The file is created by the first Put.
+func OpenKeystore(path string) *Keystore {
+	return &Keystore{Path: path}
+}
+
+// Put encrypts secret with passphrase and stores it as name, replacing any previous entry
+func (k *Keystore) Put(name string, secret string, passphrase []byte) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	file, err := k.read()
+	if err != nil {
+		return err
+	}
+	iterations := k.Iterations
+	if iterations <= 0 {
+		iterations = DefaultKeystoreIterations
+	}
+	if iterations > maxKeystoreIterations {
+		return fmt.Errorf("failed to store secret : invalid kdf iterations %d, at most %d", iterations, maxKeystoreIterations)
+	}
+	entry := &keystoreEntry{
+		KDF:        keystoreKDF,
+		Iterations: iterations,
+		Salt:       make([]byte, keystoreSaltSize),
+	}
+	if _, err := rand.Read(entry.Salt); err != nil {
+		return fmt.Errorf("failed to store secret : %w", err)
+	}
+	aead, err := entry.aead(passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to store secret : %w", err)
+	}
+	entry.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(entry.Nonce); err != nil {
+		return fmt.Errorf("failed to store secret : %w", err)
+	}
+	// the name is authenticated so entries cannot be swapped
+	entry.Ciphertext = aead.Seal(nil, entry.Nonce, []byte(secret), []byte(name))
+
+	file.Entries[name] = entry
+	return k.write(file)
+}
+
+// Get decrypts the entry name with passphrase
+func (k *Keystore) Get(name string, passphrase []byte) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	file, err := k.read()
+	if err != nil {
+		return "", err
+	}
+	entry, ok := file.Entries[name]
+	if !ok {
+		return "", fmt.Errorf("%w : %s", ErrSecretNotFound, name)
+	}
+	if entry.KDF != keystoreKDF {
+		return "", fmt.Errorf("failed to read secret %s : unsupported kdf %q", name, entry.KDF)
+	}
+	aead, err := entry.aead(passphrase)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s : %w", name, err)
+	}
+	secret, err := aead.Open(nil, entry.Nonce, entry.Ciphertext, []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("%w : %s", ErrWrongPassphrase, name)
+	}
+	return string(secret), nil
+}
+
+// Has returns true if the keystore has an entry name
+func (k *Keystore) Has(name string) (bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	file, err := k.read()
+	if err != nil {
+		return false, err
+	}
+	_, ok := file.Entries[name]
+	return ok, nil
+}
+
+// Delete removes the entry name. Deleting a missing entry is not an error.
+func (k *Keystore) Delete(name string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	file, err := k.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := file.Entries[name]; !ok {
+		return nil
+	}
+	delete(file.Entries, name)
+	return k.write(file)
+}
+
+// Names returns the names of the entries, sorted
+func (k *Keystore) Names() ([]string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	file, err := k.read()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(file.Entries))
+	for name := range file.Entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// read loads the keystore file, returning an empty keystore if it does not exist. k.mu must be held.
+func (k *Keystore) read() (*keystoreFile, error) {
+	file := &keystoreFile{Version: keystoreVersion, Entries: make(map[string]*keystoreEntry)}
+	b, err := os.ReadFile(k.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return file, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore : %w", err)
+	}
+	if err := json.Unmarshal(b, file); err != nil {
+		return nil, fmt.Errorf("failed to parse keystore %s : %w", k.Path, err)
+	}
+	if file.Version != keystoreVersion {
+		return nil, fmt.Errorf("failed to parse keystore %s : unsupported version %d", k.Path, file.Version)
+	}
+	if file.Entries == nil {
+		file.Entries = make(map[string]*keystoreEntry)
+	}
+	return file, nil
+}
+
+// write saves the keystore file readable by the owner only, replacing it atomically. k.mu must be held.
+func (k *Keystore) write(file *keystoreFile) error {
+	b, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(k.Path), 0700); err != nil {
+		return fmt.Errorf("failed to write keystore : %w", err)
+	}
+	tmp := k.Path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write keystore : %w", err)
+	}
+	if err := os.Rename(tmp, k.Path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write keystore : %w", err)
+	}
+	return nil
+}
+
+// aead returns the AES-256-GCM cipher keyed from passphrase and the entry's salt
+func (e *keystoreEntry) aead(passphrase []byte) (cipher.AEAD, error) {
+	if e.Iterations <= 0 || e.Iterations > maxKeystoreIterations {
+		return nil, fmt.Errorf("invalid kdf iterations %d", e.Iterations)
+	}
+	block, err := aes.NewCipher(pbkdf2SHA256(passphrase, e.Salt, e.Iterations, keystoreKeySize))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2SHA256 derives a key of keyLen bytes from password and salt as specified by RFC 8018
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var key []byte
+	u := make([]byte, 0, sha256.Size)
+	t := make([]byte, sha256.Size)
+	var counter [4]byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		binary.BigEndian.PutUint32(counter[:], block)
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(counter[:])
+		u = prf.Sum(u[:0])
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
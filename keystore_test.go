@@ -0,0 +1,164 @@
+package rls
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPBKDF2SHA256 checks the vectors of RFC 7914 section 11 and the PBKDF2-HMAC-SHA256 counterparts
+// of the RFC 6070 vectors
+func TestPBKDF2SHA256(t *testing.T) {
+	tests := []struct {
+		password, salt string
+		iterations     int
+		keyLen         int
+		want           string
+	}{
+		{"passwd", "salt", 1, 64, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{"Password", "NaCl", 80000, 64, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+		{"password", "salt", 1, 32, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{"password", "salt", 2, 32, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{"password", "salt", 4096, 32, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+		{"passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", 4096, 40, "348c89dbcbd32b2f32d814b8116e84cf2b17347ebc1800181c4e2a1fb8dd53e1c635518c7dac47e9"},
+		{"pass\x00word", "sa\x00lt", 4096, 16, "89b69d0516f829893c696226650a8687"},
+	}
+	for _, tt := range tests {
+		got := hex.EncodeToString(pbkdf2SHA256([]byte(tt.password), []byte(tt.salt), tt.iterations, tt.keyLen))
+		if got != tt.want {
+			t.Errorf("pbkdf2SHA256(%q, %q, %d, %d) = %s, want %s", tt.password, tt.salt, tt.iterations, tt.keyLen, got, tt.want)
+		}
+	}
+}
+
+// testKeystore returns a keystore in a temporary directory with few iterations, to keep tests fast
+func testKeystore(t *testing.T) *Keystore {
+	keystore := OpenKeystore(filepath.Join(t.TempDir(), "keystore.json"))
+	keystore.Iterations = 1000
+	return keystore
+}
+
+func TestKeystore(t *testing.T) {
+	keystore := testKeystore(t)
+	passphrase := []byte("correct horse")
+	if ok, err := keystore.Has("prod/api_key"); err != nil || ok {
+		t.Fatalf("empty keystore: %v, %v", ok, err)
+	}
+	if err := keystore.Put("prod/api_key", "s3cr3t_value", passphrase); err != nil {
+		t.Fatal(err)
+	}
+	if err := keystore.Put("prod/webhook_secret", "hook_value", passphrase); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := keystore.Get("prod/api_key", passphrase); err != nil || got != "s3cr3t_value" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if _, err := keystore.Get("prod/api_key", []byte("wrong")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected a wrong passphrase error, got %v", err)
+	}
+	if _, err := keystore.Get("missing", passphrase); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+
+	b, err := os.ReadFile(keystore.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "s3cr3t_value") || strings.Contains(string(b), "hook_value") {
+		t.Error("secret stored in clear")
+	}
+	if info, err := os.Stat(keystore.Path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("keystore mode %v, %v", info.Mode(), err)
+	}
+
+	if err := keystore.Delete("prod/webhook_secret"); err != nil {
+		t.Fatal(err)
+	}
+	if names, err := keystore.Names(); err != nil || !reflect.DeepEqual(names, []string{"prod/api_key"}) {
+		t.Errorf("got names %v, %v", names, err)
+	}
+}
+
+func TestKeystoreEntriesCannotBeSwapped(t *testing.T) {
+	keystore := testKeystore(t)
+	passphrase := []byte("pass")
+	if err := keystore.Put("a", "secret a", passphrase); err != nil {
+		t.Fatal(err)
+	}
+	file, err := keystore.read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Entries["b"] = file.Entries["a"]
+	if err := keystore.write(file); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keystore.Get("b", passphrase); err == nil {
+		t.Error("expected an error decrypting an entry under another name")
+	}
+}
+
+func TestKeystoreIterationsBound(t *testing.T) {
+	keystore := testKeystore(t)
+	passphrase := []byte("pass")
+	if err := keystore.Put("a", "secret a", passphrase); err != nil {
+		t.Fatal(err)
+	}
+	file, err := keystore.read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Entries["a"].Iterations = 1 << 31
+	if err := keystore.write(file); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := keystore.Get("a", passphrase); err == nil || !strings.Contains(err.Error(), "invalid kdf iterations") {
+		t.Errorf("expected an iterations error, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("key derived before checking the iterations")
+	}
+
+	keystore.Iterations = maxKeystoreIterations + 1
+	if err := keystore.Put("b", "secret b", passphrase); err == nil {
+		t.Error("expected an error storing a secret with too many iterations")
+	}
+}
+
+func TestLoadSecretsFromKeystore(t *testing.T) {
+	keystore := testKeystore(t)
+	passphrase := func() ([]byte, error) { return []byte("pass"), nil }
+	if err := keystore.Put(KeystoreAPIKeyName("default"), "stored_key", []byte("pass")); err != nil {
+		t.Fatal(err)
+	}
+	path := writeConfig(t, "config.toml", "[profiles.default]\nbase_url = \"https://rls.example.com\"\naccount_id = \"acct\"\n")
+	opts := LoadOptions{Path: path, LookupEnv: lookupEnv(nil), Keystore: keystore, Passphrase: passphrase}
+
+	profile, err := LoadProfile(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.APIKey != "stored_key" || profile.WebhookSecret != "" || profile.Sources["api_key"] != "keystore "+keystore.Path {
+		t.Errorf("got %+v", profile)
+	}
+
+	opts.Passphrase = func() ([]byte, error) { return []byte("wrong"), nil }
+	if _, err := LoadProfile(opts); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected a wrong passphrase error, got %v", err)
+	}
+
+	if err := os.WriteFile(keystore.Path, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	opts.Passphrase = passphrase
+	if _, err := LoadProfile(opts); err == nil || !strings.Contains(err.Error(), "failed to parse keystore") {
+		t.Errorf("expected the keystore error, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package rls
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -9,7 +10,7 @@ import (
 
 // DecodedInvoice contains the result of a call to decode invoice
 type DecodedInvoice struct {
-	Amount  int64  `json:"amount"`
+	Amount  Amount `json:"amount"`
 	Memo    string `json:"memo"`
 	NodeID  string `json:"node_id"`
 	Invoice string `json:"destination"`
@@ -17,18 +18,25 @@ type DecodedInvoice struct {
 
 type FeeEstimateRequest struct {
 	Destination string `json:"destination"`
-	Amount      int64  `json:"amount"`
+	Amount      Amount `json:"amount"`
 }
 
 // FeeEstimate contains the result of a call to EstimateFee
 type FeeEstimate struct {
-	Amount  int64  `json:"amount"`
+	Amount  Amount `json:"amount"`
 	Invoice string `json:"destination"`
-	Fee     int64  `json:"fee"`
+	Fee     Amount `json:"fee"`
 }
 
 // DecodeInvoice decodes a Lightning Invoice using RLS using `lncli decodepayreq`
+//
+// Deprecated: use DecodeInvoiceContext
 func (rls *RLSClient) DecodeInvoice(invoice string) (*DecodedInvoice, error) {
+	return rls.DecodeInvoiceContext(rls.context(), invoice)
+}
+
+// DecodeInvoiceContext decodes a Lightning Invoice using RLS using `lncli decodepayreq`
+func (rls *RLSClient) DecodeInvoiceContext(ctx context.Context, invoice string) (*DecodedInvoice, error) {
 	data := map[string]string{
 		"destination": invoice,
 	}
@@ -48,15 +56,26 @@ func (rls *RLSClient) DecodeInvoice(invoice string) (*DecodedInvoice, error) {
 	}
 
 	var decodedInvoice DecodedInvoice
-	err = rls.sendRequest(req, &decodedInvoice)
+	err = rls.sendRequest(ctx, OpDecodeInvoice, req, &decodedInvoice)
 	if err != nil {
 		return nil, err
 	}
 	return &decodedInvoice, nil
 }
 
-// EstimateLightningFee estimates Lightning Fee of an invoice using `lncli`
+// EstimateLightningFee estimates Lightning Fee of paying amount sats to an invoice using `lncli`
+//
+// Deprecated: use EstimateLightningFeeContext
 func (rls *RLSClient) EstimateLightningFee(invoice string, amount int64) (*FeeEstimate, error) {
+	return rls.EstimateLightningFeeContext(rls.context(), invoice, Sats(amount))
+}
+
+// EstimateLightningFeeContext estimates Lightning Fee of an invoice using `lncli`.
+// An amount that is not whole sats is rejected before sending.
+func (rls *RLSClient) EstimateLightningFeeContext(ctx context.Context, invoice string, amount Amount) (*FeeEstimate, error) {
+	if err := checkWholeSats("amount", amount); err != nil {
+		return nil, fmt.Errorf("failed to estimate fee : %w", err)
+	}
 	feeEstimateReq := FeeEstimateRequest{
 		Destination: invoice,
 		Amount:      amount,
@@ -77,7 +96,7 @@ func (rls *RLSClient) EstimateLightningFee(invoice string, amount int64) (*FeeEs
 	}
 
 	var feeEstimate FeeEstimate
-	err = rls.sendRequest(req, &feeEstimate)
+	err = rls.sendRequest(ctx, OpEstimateLightningFee, req, &feeEstimate)
 	if err != nil {
 		return nil, err
 	}
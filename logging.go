@@ -0,0 +1,194 @@
+package rls
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	// redactedHeaders are replaced in logged headers
+	redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", WebhookHeaderKey}
+	// secretFieldPattern matches JSON string fields holding secrets, e.g. the webhook secret
+	secretFieldPattern = regexp.MustCompile(`("(?:[a-z_]*secret|api_key|password)"\s*:\s*)"[^"]*"`)
+	// invoicePattern matches BOLT-11 invoices on mainnet, testnet, signet and regtest
+	invoicePattern = regexp.MustCompile(`(?i)\b(ln(?:bcrt|bc|tbs|tb|sb))[0-9a-z]{20,}`)
+)
+
+// Logger receives a structured LogEntry for every attempt of an RLS API call
+type Logger interface {
+	Log(entry LogEntry)
+}
+
+// LogEntry describes a single attempt of an RLS API call. Credentials and secrets are redacted.
+type LogEntry struct {
+	Time      time.Time
+	Operation string
+	Method    string
+	Path      string
+	Status    int
+	Latency   time.Duration
+	Attempt   int
+	RequestID string
+	Err       error
+	// RequestHeader, RequestBody and ResponseBody are only set if LogOptions.Bodies is set
+	RequestHeader http.Header
+	RequestBody   string
+	ResponseBody  string
+}
+
+// LogOptions configures what the logging middleware records
+type LogOptions struct {
+	// Bodies includes the request headers and the request and response bodies in each entry
+	Bodies bool
+	// RedactInvoices replaces BOLT-11 invoices in bodies with their prefix
+	RedactInvoices bool
+}
+
+// WithLogger logs every attempt of every RLS API call to logger
+func WithLogger(logger Logger, opts LogOptions) Option {
+	return WithMiddleware(LoggingMiddleware(logger, opts))
+}
+
+// LoggingMiddleware returns a Middleware logging every attempt to logger
+func LoggingMiddleware(logger Logger, opts LogOptions) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			entry := LogEntry{
+				Time:      time.Now(),
+				Operation: Operation(ctx),
+				Method:    req.Method,
+				Path:      req.URL.Path,
+				Attempt:   Attempt(ctx),
+				RequestID: RequestID(ctx),
+			}
+			if opts.Bodies {
+				entry.RequestHeader = redactHeader(req.Header)
+				entry.RequestBody = opts.redactBody(readRequestBody(req))
+			}
+
+			res, err := next.RoundTrip(req)
+			entry.Latency = time.Since(entry.Time)
+			entry.Err = err
+			if res != nil {
+				entry.Status = res.StatusCode
+				if opts.Bodies {
+					var body []byte
+					body, res.Body = readResponseBody(res)
+					entry.ResponseBody = opts.redactBody(string(body))
+				}
+			}
+			logger.Log(entry)
+			return res, err
+		})
+	}
+}
+
+// redactBody removes secrets, and optionally invoices, from a request or response body
+func (opts LogOptions) redactBody(body string) string {
+	body = secretFieldPattern.ReplaceAllString(body, `$1"`+redacted+`"`)
+	if opts.RedactInvoices {
+		body = RedactInvoices(body)
+	}
+	return body
+}
+
+// RedactInvoices replaces every BOLT-11 invoice in s with its human readable prefix
+func RedactInvoices(s string) string {
+	return invoicePattern.ReplaceAllString(s, "$1..."+redacted)
+}
+
+// redactHeader returns a copy of header with credentials redacted
+func redactHeader(header http.Header) http.Header {
+	clone := header.Clone()
+	for _, key := range redactedHeaders {
+		if clone.Get(key) != "" {
+			clone.Set(key, redacted)
+		}
+	}
+	return clone
+}
+
+// readRequestBody returns a copy of the request body without consuming it
+func readRequestBody(req *http.Request) string {
+	if req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	b, _ := io.ReadAll(body)
+	return string(b)
+}
+
+// readResponseBody reads the response body and returns it along with a replacement body
+func readResponseBody(res *http.Response) ([]byte, io.ReadCloser) {
+	b, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return b, io.NopCloser(io.MultiReader(bytes.NewReader(b), errReader{err}))
+	}
+	return b, io.NopCloser(bytes.NewReader(b))
+}
+
+// errReader replays a read error after the bytes read before it
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// TextLogger is a Logger writing one logfmt line per entry
+type TextLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewTextLogger returns a TextLogger writing to w
+func NewTextLogger(w io.Writer) *TextLogger {
+	return &TextLogger{w: w}
+}
+
+// Log implements Logger
+func (l *TextLogger) Log(entry LogEntry) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "time=%s op=%s method=%s path=%s status=%d latency=%s attempt=%d request_id=%s",
+		entry.Time.Format(time.RFC3339Nano), entry.Operation, entry.Method, entry.Path,
+		entry.Status, entry.Latency, entry.Attempt, entry.RequestID)
+	if entry.Err != nil {
+		fmt.Fprintf(&b, " err=%s", strconv.Quote(entry.Err.Error()))
+	}
+	keys := make([]string, 0, len(entry.RequestHeader))
+	for key := range entry.RequestHeader {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, " header.%s=%s", key, strconv.Quote(strings.Join(entry.RequestHeader[key], ",")))
+	}
+	if entry.RequestBody != "" {
+		fmt.Fprintf(&b, " request_body=%s", strconv.Quote(entry.RequestBody))
+	}
+	if entry.ResponseBody != "" {
+		fmt.Fprintf(&b, " response_body=%s", strconv.Quote(entry.ResponseBody))
+	}
+	b.WriteByte('\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = io.WriteString(l.w, b.String())
+}
@@ -0,0 +1,75 @@
+package rls
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingLogger keeps every entry logged
+type recordingLogger struct {
+	mu      sync.Mutex
+	entries []LogEntry
+}
+
+func (l *recordingLogger) Log(entry LogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry)
+}
+
+func TestLoggingRedactsCredentials(t *testing.T) {
+	const invoice = "lnbcrt10u1pj9x7h3pp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypq"
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"wh_1","secret":"topsecret","callback_url":"https://example.com"}`))
+	})
+	logger := &recordingLogger{}
+	client.Use(LoggingMiddleware(logger, LogOptions{Bodies: true, RedactInvoices: true}))
+	if _, err := client.SubscribeToWebhookContext(context.Background(), "https://example.com/"+invoice); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(logger.entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(logger.entries))
+	}
+	entry := logger.entries[0]
+	if entry.Operation != OpSubscribeToWebhook || entry.Status != http.StatusOK || entry.Attempt != 1 || entry.RequestID == "" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if got := entry.RequestHeader.Get("Authorization"); got != redacted {
+		t.Errorf("Authorization logged as %q", got)
+	}
+	if strings.Contains(entry.ResponseBody, "topsecret") || !strings.Contains(entry.ResponseBody, redacted) {
+		t.Errorf("secret not redacted from %q", entry.ResponseBody)
+	}
+	if strings.Contains(entry.RequestBody, invoice) || !strings.Contains(entry.RequestBody, "lnbcrt..."+redacted) {
+		t.Errorf("invoice not redacted from %q", entry.RequestBody)
+	}
+}
+
+func TestLoggingKeepsResponseBody(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"wd_1"}`))
+	})
+	client.Use(LoggingMiddleware(&recordingLogger{}, LogOptions{Bodies: true}))
+	if wd, err := client.GetWithdrawalContext(context.Background(), "wd_1"); err != nil || wd.ID != "wd_1" {
+		t.Fatalf("response body consumed by the logger: %v, %v", wd, err)
+	}
+}
+
+func TestTextLogger(t *testing.T) {
+	var buf bytes.Buffer
+	NewTextLogger(&buf).Log(LogEntry{Operation: OpGetAccount, Method: http.MethodGet, Path: "/accounts/acct", Status: 200, Attempt: 1, RequestID: "req"})
+	line := buf.String()
+	for _, want := range []string{"op=" + OpGetAccount, "method=GET", "path=/accounts/acct", "status=200", "attempt=1", "request_id=req"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("%q missing from %q", want, line)
+		}
+	}
+	if !strings.HasSuffix(line, "\n") || strings.Count(line, "\n") != 1 {
+		t.Errorf("expected a single line, got %q", line)
+	}
+}
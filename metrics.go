@@ -0,0 +1,221 @@
+package rls
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// StatusClassError is the status class recorded for attempts that failed without a response
+const StatusClassError = "error"
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the latency histogram buckets used by NewMetrics
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// MetricsRecorder receives measurements for every attempt of an RLS API call
+type MetricsRecorder interface {
+	// AddInFlight adds delta to the number of in-flight attempts of op
+	AddInFlight(op string, delta int)
+	// ObserveAttempt records a finished attempt of op. statusClass is "2xx", "4xx", "5xx", etc.
+	// or StatusClassError, and attempt starts at 1, so attempts above 1 are retries.
+	ObserveAttempt(op string, statusClass string, latency time.Duration, attempt int)
+}
+
+// WithMetrics records metrics for every attempt of every RLS API call in recorder
+func WithMetrics(recorder MetricsRecorder) Option {
+	return WithMiddleware(MetricsMiddleware(recorder))
+}
+
+// MetricsMiddleware returns a Middleware recording metrics in recorder
+func MetricsMiddleware(recorder MetricsRecorder) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			op := Operation(ctx)
+			recorder.AddInFlight(op, 1)
+			defer recorder.AddInFlight(op, -1)
+
+			start := time.Now()
+			res, err := next.RoundTrip(req)
+			statusClass := StatusClassError
+			if err == nil {
+				statusClass = fmt.Sprintf("%dxx", res.StatusCode/100)
+			}
+			recorder.ObserveAttempt(op, statusClass, time.Since(start), Attempt(ctx))
+			return res, err
+		})
+	}
+}
+
+// Metrics is an in-memory MetricsRecorder that can be exported through expvar or
+// in the Prometheus text exposition format
+type Metrics struct {
+	mu         sync.Mutex
+	buckets    []float64
+	operations map[string]*operationMetrics
+}
+
+// operationMetrics holds the metrics of a single operation
+type operationMetrics struct {
+	Requests     map[string]int64 `json:"requests"`
+	Retries      int64            `json:"retries"`
+	InFlight     int64            `json:"in_flight"`
+	BucketCounts []int64          `json:"latency_bucket_counts"`
+	LatencySum   float64          `json:"latency_sum_seconds"`
+	LatencyCount int64            `json:"latency_count"`
+}
+
+// NewMetrics returns an empty Metrics using DefaultLatencyBuckets
+func NewMetrics() *Metrics {
+	return NewMetricsWithBuckets(DefaultLatencyBuckets)
+}
+
+// NewMetricsWithBuckets returns an empty Metrics using the given latency bucket upper bounds in seconds
+func NewMetricsWithBuckets(buckets []float64) *Metrics {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &Metrics{
+		buckets:    sorted,
+		operations: make(map[string]*operationMetrics),
+	}
+}
+
+// operation returns the metrics of op, creating them if needed. m.mu must be held.
+func (m *Metrics) operation(op string) *operationMetrics {
+	om, ok := m.operations[op]
+	if !ok {
+		om = &operationMetrics{
+			Requests:     make(map[string]int64),
+			BucketCounts: make([]int64, len(m.buckets)),
+		}
+		m.operations[op] = om
+	}
+	return om
+}
+
+// AddInFlight implements MetricsRecorder
+func (m *Metrics) AddInFlight(op string, delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.operation(op).InFlight += int64(delta)
+}
+
+// ObserveAttempt implements MetricsRecorder
+func (m *Metrics) ObserveAttempt(op string, statusClass string, latency time.Duration, attempt int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	om := m.operation(op)
+	om.Requests[statusClass]++
+	if attempt > 1 {
+		om.Retries++
+	}
+	seconds := latency.Seconds()
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			om.BucketCounts[i]++
+		}
+	}
+	om.LatencySum += seconds
+	om.LatencyCount++
+}
+
+// snapshot returns a deep copy of the metrics keyed by operation
+func (m *Metrics) snapshot() map[string]operationMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := make(map[string]operationMetrics, len(m.operations))
+	for op, om := range m.operations {
+		cp := *om
+		cp.Requests = make(map[string]int64, len(om.Requests))
+		for class, count := range om.Requests {
+			cp.Requests[class] = count
+		}
+		cp.BucketCounts = append([]int64(nil), om.BucketCounts...)
+		snapshot[op] = cp
+	}
+	return snapshot
+}
+
+// PublishExpvar exports the metrics as the expvar variable name, served by expvar at /debug/vars.
+// Like expvar.Publish, it panics if name is already in use.
+func (m *Metrics) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return m.snapshot()
+	}))
+}
+
+// Handler returns an http.Handler serving the metrics in the Prometheus text exposition format
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = m.WritePrometheus(w)
+	})
+}
+
+// WritePrometheus writes the metrics to w in the Prometheus text exposition format
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	snapshot := m.snapshot()
+	ops := make([]string, 0, len(snapshot))
+	for op := range snapshot {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	pw := &promWriter{w: w}
+	pw.header("rls_client_requests_total", "counter", "Attempts of RLS API calls by operation and status class.")
+	for _, op := range ops {
+		classes := make([]string, 0, len(snapshot[op].Requests))
+		for class := range snapshot[op].Requests {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		for _, class := range classes {
+			pw.printf("rls_client_requests_total{operation=%q,code=%q} %d\n", op, class, snapshot[op].Requests[class])
+		}
+	}
+
+	pw.header("rls_client_retries_total", "counter", "Retried attempts of RLS API calls by operation.")
+	for _, op := range ops {
+		pw.printf("rls_client_retries_total{operation=%q} %d\n", op, snapshot[op].Retries)
+	}
+
+	pw.header("rls_client_in_flight_requests", "gauge", "In-flight attempts of RLS API calls by operation.")
+	for _, op := range ops {
+		pw.printf("rls_client_in_flight_requests{operation=%q} %d\n", op, snapshot[op].InFlight)
+	}
+
+	pw.header("rls_client_request_duration_seconds", "histogram", "Latency of RLS API call attempts by operation.")
+	for _, op := range ops {
+		om := snapshot[op]
+		for i, bound := range m.buckets {
+			le := strconv.FormatFloat(bound, 'g', -1, 64)
+			pw.printf("rls_client_request_duration_seconds_bucket{operation=%q,le=%q} %d\n", op, le, om.BucketCounts[i])
+		}
+		pw.printf("rls_client_request_duration_seconds_bucket{operation=%q,le=\"+Inf\"} %d\n", op, om.LatencyCount)
+		pw.printf("rls_client_request_duration_seconds_sum{operation=%q} %s\n", op, strconv.FormatFloat(om.LatencySum, 'g', -1, 64))
+		pw.printf("rls_client_request_duration_seconds_count{operation=%q} %d\n", op, om.LatencyCount)
+	}
+	return pw.err
+}
+
+// promWriter writes the exposition format, keeping the first error
+type promWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (pw *promWriter) printf(format string, args ...interface{}) {
+	if pw.err != nil {
+		return
+	}
+	_, pw.err = fmt.Fprintf(pw.w, format, args...)
+}
+
+func (pw *promWriter) header(name, kind, help string) {
+	pw.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
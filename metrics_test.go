@@ -0,0 +1,60 @@
+package rls
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	calls := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+	client.RetryPolicy = fastRetryPolicy()
+	metrics := NewMetrics()
+	client.Use(MetricsMiddleware(metrics))
+	if _, err := client.GetAccountContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	om := metrics.snapshot()[OpGetAccount]
+	if om.Requests["5xx"] != 1 || om.Requests["2xx"] != 1 || om.Retries != 1 || om.InFlight != 0 || om.LatencyCount != 2 {
+		t.Errorf("unexpected metrics %+v", om)
+	}
+}
+
+func TestMetricsHistogram(t *testing.T) {
+	metrics := NewMetricsWithBuckets([]float64{1, 0.1})
+	metrics.ObserveAttempt(OpGetAccount, "2xx", 50*time.Millisecond, 1)
+	metrics.ObserveAttempt(OpGetAccount, "2xx", 500*time.Millisecond, 1)
+	metrics.ObserveAttempt(OpGetAccount, StatusClassError, 5*time.Second, 2)
+
+	var buf bytes.Buffer
+	if err := metrics.WritePrometheus(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`rls_client_requests_total{operation="GetAccount",code="2xx"} 2`,
+		`rls_client_requests_total{operation="GetAccount",code="error"} 1`,
+		`rls_client_retries_total{operation="GetAccount"} 1`,
+		`rls_client_request_duration_seconds_bucket{operation="GetAccount",le="0.1"} 1`,
+		`rls_client_request_duration_seconds_bucket{operation="GetAccount",le="1"} 2`,
+		`rls_client_request_duration_seconds_bucket{operation="GetAccount",le="+Inf"} 3`,
+		`rls_client_request_duration_seconds_count{operation="GetAccount"} 3`,
+		"# TYPE rls_client_request_duration_seconds histogram",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("%q missing from output:\n%s", want, out)
+		}
+	}
+}
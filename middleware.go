@@ -0,0 +1,98 @@
+package rls
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// RoundTripperFunc adapts an ordinary function to an http.RoundTripper
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps the RoundTripper that sends each attempt of an RLS API call.
+// The operation name and attempt number are available from the request's context
+// through Operation and Attempt.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// Hooks are callbacks run around every attempt of an RLS API call
+type Hooks struct {
+	// Before is called before the request is sent
+	Before func(op string, req *http.Request)
+	// After is called once the response (or error) is received
+	After func(op string, req *http.Request, res *http.Response, latency time.Duration, err error)
+}
+
+// Middleware returns a Middleware running the hooks
+func (h Hooks) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			op := Operation(req.Context())
+			if h.Before != nil {
+				h.Before(op, req)
+			}
+			start := time.Now()
+			res, err := next.RoundTrip(req)
+			if h.After != nil {
+				h.After(op, req, res, time.Since(start), err)
+			}
+			return res, err
+		})
+	}
+}
+
+// RequestIDHeader carries the ID generated for each RLS API call. All attempts of a call share the same ID.
+const RequestIDHeader = "X-Request-Id"
+
+type contextKey int
+
+const (
+	operationContextKey contextKey = iota
+	attemptContextKey
+	requestIDContextKey
+)
+
+// Operation returns the name of the RLS API operation (e.g. OpGetAccount) a request belongs to
+func Operation(ctx context.Context) string {
+	op, _ := ctx.Value(operationContextKey).(string)
+	return op
+}
+
+// Attempt returns the attempt number (starting at 1) of a request
+func Attempt(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptContextKey).(int)
+	return attempt
+}
+
+// RequestID returns the ID of the RLS API call a request belongs to
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey).(string)
+	return requestID
+}
+
+// WithMiddleware adds middleware to the client. The first middleware is the outermost.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(o *clientOptions) error {
+		o.middleware = append(o.middleware, middleware...)
+		return nil
+	}
+}
+
+// Use adds middleware to the client. The first middleware is the outermost.
+// Use is not safe to call concurrently with requests.
+func (rls *RLSClient) Use(middleware ...Middleware) {
+	rls.middleware = append(rls.middleware, middleware...)
+}
+
+// roundTripper returns the client's HTTPClient wrapped in its middleware
+func (rls *RLSClient) roundTripper() http.RoundTripper {
+	var rt http.RoundTripper = RoundTripperFunc(rls.HTTPClient.Do)
+	for i := len(rls.middleware) - 1; i >= 0; i-- {
+		rt = rls.middleware[i](rt)
+	}
+	return rt
+}
@@ -0,0 +1,61 @@
+package rls
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMiddlewareOrderAndContext(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				calls = append(calls, name+" "+Operation(req.Context()))
+				if Attempt(req.Context()) != 1 || RequestID(req.Context()) == "" || req.Header.Get(RequestIDHeader) != RequestID(req.Context()) {
+					t.Errorf("%s: missing attempt or request ID", name)
+				}
+				return next.RoundTrip(req)
+			})
+		}
+	}
+	client.Use(trace("outer"), trace("inner"))
+	if _, err := client.GetAccountContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"outer " + OpGetAccount, "inner " + OpGetAccount}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("got %q, want %q", calls, want)
+	}
+}
+
+func TestMiddlewareSeesEveryAttempt(t *testing.T) {
+	calls := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+	client.RetryPolicy = fastRetryPolicy()
+	var attempts []int
+	var requestIDs []string
+	client.Use(Hooks{
+		After: func(op string, req *http.Request, res *http.Response, latency time.Duration, err error) {
+			attempts = append(attempts, Attempt(req.Context()))
+			requestIDs = append(requestIDs, RequestID(req.Context()))
+		},
+	}.Middleware())
+	if _, err := client.GetAccountContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(attempts, []int{1, 2}) || requestIDs[0] != requestIDs[1] {
+		t.Errorf("got attempts %v with request IDs %q", attempts, requestIDs)
+	}
+}
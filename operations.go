@@ -0,0 +1,20 @@
+package rls
+
+// Operation names identify each RLS API call made by RLSClient
+const (
+	OpPing                 = "Ping"
+	OpGetAccount           = "GetAccount"
+	OpNewWithdrawal        = "NewWithdrawal"
+	OpGetWithdrawal        = "GetWithdrawal"
+	OpListWithdrawals      = "ListWithdrawals"
+	OpNewInvoice           = "NewInvoice"
+	OpGetInvoice           = "GetInvoice"
+	OpGetInvoices          = "GetInvoices"
+	OpGetDeposit           = "GetDeposit"
+	OpGetDeposits          = "GetDeposits"
+	OpSubscribeToWebhook   = "SubscribeToWebhook"
+	OpGetSubscribedWebhook = "GetSubscribedWebhook"
+	OpDeleteWebhook        = "DeleteWebhook"
+	OpDecodeInvoice        = "DecodeInvoice"
+	OpEstimateLightningFee = "EstimateLightningFee"
+)
@@ -0,0 +1,258 @@
+package rls
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Version is the version of this client, reported in the default User-Agent
+const Version = "0.2.0"
+
+const (
+	// DefaultTimeout bounds a single HTTP attempt made by clients created with New
+	DefaultTimeout = 30 * time.Second
+	// DefaultMaxIdleConns is the size of the idle connection pool of clients created with New
+	DefaultMaxIdleConns = 100
+	// DefaultUserAgent is the User-Agent sent by clients created with New
+	DefaultUserAgent = "rls-client-go/" + Version
+
+	defaultDialTimeout         = 10 * time.Second
+	defaultKeepAlive           = 30 * time.Second
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultTLSHandshakeTimeout = 10 * time.Second
+	defaultMaxIdleConnsPerHost = 10
+)
+
+// Option configures an RLSClient created by New
+type Option func(*clientOptions) error
+
+// clientOptions collects the Options passed to New before the client is built
+type clientOptions struct {
+	ctx           context.Context
+	httpClient    *http.Client
+	timeout       time.Duration
+	timeouts      operationTimeouts
+	userAgent     string
+	maxIdleConns  int
+	proxy         func(*http.Request) (*url.URL, error)
+	unixSocket    string
+	tlsConfig     *tls.Config
+	retryPolicy   *RetryPolicy
+	middleware    []Middleware
+	timestampUnit TimestampUnit
+
+	// transportTuned records whether an option that configures the default transport was passed
+	transportTuned bool
+}
+
+// operationTimeouts bound the total duration of an operation, including retries
+type operationTimeouts struct {
+	read  time.Duration
+	write time.Duration
+}
+
+// forOperation returns the timeout that applies to op, or 0 if op is unbounded
+func (t operationTimeouts) forOperation(op string) time.Duration {
+	switch op {
+	case OpNewWithdrawal, OpNewInvoice, OpSubscribeToWebhook, OpDeleteWebhook:
+		return t.write
+	}
+	return t.read
+}
+
+// WithContext sets the context used by the deprecated methods that do not take a context
+func WithContext(ctx context.Context) Option {
+	return func(o *clientOptions) error {
+		o.ctx = ctx
+		return nil
+	}
+}
+
+// WithHTTPClient makes the client send requests with httpClient as-is. It cannot be combined
+// with the options that tune the default transport (WithMaxIdleConns, WithProxy, WithUnixSocket,
+// WithTLSConfig).
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o *clientOptions) error {
+		if httpClient == nil {
+			return errors.New("http client must not be nil")
+		}
+		o.httpClient = httpClient
+		return nil
+	}
+}
+
+// WithTimeout bounds each HTTP attempt. A zero timeout disables it.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *clientOptions) error {
+		if timeout < 0 {
+			return fmt.Errorf("invalid timeout %s", timeout)
+		}
+		o.timeout = timeout
+		return nil
+	}
+}
+
+// WithPerOperationTimeouts bounds the total duration of each operation, including retries.
+// The write timeout applies to the operations that create or change something on RLS (NewWithdrawal,
+// NewInvoice, SubscribeToWebhook and DeleteWebhook) and the read timeout to every other operation.
+// A zero timeout leaves the corresponding operations unbounded.
+func WithPerOperationTimeouts(read, write time.Duration) Option {
+	return func(o *clientOptions) error {
+		if read < 0 || write < 0 {
+			return fmt.Errorf("invalid operation timeouts %s/%s", read, write)
+		}
+		o.timeouts = operationTimeouts{read: read, write: write}
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(o *clientOptions) error {
+		o.userAgent = userAgent
+		return nil
+	}
+}
+
+// WithMaxIdleConns sets the size of the idle connection pool of the default transport
+func WithMaxIdleConns(n int) Option {
+	return func(o *clientOptions) error {
+		if n < 0 {
+			return fmt.Errorf("invalid max idle conns %d", n)
+		}
+		o.maxIdleConns = n
+		o.transportTuned = true
+		return nil
+	}
+}
+
+// WithProxy routes requests through the proxy at proxyURL instead of the proxy from the environment
+func WithProxy(proxyURL string) Option {
+	return func(o *clientOptions) error {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return fmt.Errorf("invalid proxy url : %w", err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid proxy url %q : scheme and host are required", proxyURL)
+		}
+		o.proxy = http.ProxyURL(u)
+		o.transportTuned = true
+		return nil
+	}
+}
+
+// WithUnixSocket sends every request over the unix domain socket at path, e.g. to reach RLS through a local sidecar
+func WithUnixSocket(path string) Option {
+	return func(o *clientOptions) error {
+		if path == "" {
+			return errors.New("unix socket path must not be empty")
+		}
+		o.unixSocket = path
+		o.transportTuned = true
+		return nil
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the default transport
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(o *clientOptions) error {
+		o.tlsConfig = tlsConfig
+		o.transportTuned = true
+		return nil
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy. A nil policy disables retries.
+func WithRetryPolicy(policy *RetryPolicy) Option {
+	return func(o *clientOptions) error {
+		o.retryPolicy = policy
+		return nil
+	}
+}
+
+// WithTimestampUnit sets the unit of the time-based cursors sent to RLS, instead of detecting it
+func WithTimestampUnit(unit TimestampUnit) Option {
+	return func(o *clientOptions) error {
+		if unit < TimestampAuto || unit > TimestampMilliseconds {
+			return fmt.Errorf("invalid timestamp unit %d", unit)
+		}
+		o.timestampUnit = unit
+		return nil
+	}
+}
+
+// New creates a new RLSClient with production-ready defaults: a tuned transport honoring the proxy
+// environment variables, a DefaultTimeout per attempt, DefaultUserAgent and DefaultRetryPolicy
+func New(cfg Config, opts ...Option) (*RLSClient, error) {
+	o := &clientOptions{
+		ctx:          context.Background(),
+		timeout:      DefaultTimeout,
+		userAgent:    DefaultUserAgent,
+		maxIdleConns: DefaultMaxIdleConns,
+		proxy:        http.ProxyFromEnvironment,
+		retryPolicy:  DefaultRetryPolicy(),
+	}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, fmt.Errorf("failed to create rls client : %w", err)
+		}
+	}
+
+	httpClient := o.httpClient
+	if httpClient == nil {
+		httpClient = &http.Client{
+			Transport: o.transport(),
+			Timeout:   o.timeout,
+		}
+	} else if o.transportTuned {
+		return nil, errors.New("failed to create rls client : WithHTTPClient cannot be combined with transport options")
+	}
+
+	return &RLSClient{
+		Ctx:           o.ctx,
+		cfg:           cfg,
+		HTTPClient:    httpClient,
+		RetryPolicy:   o.retryPolicy,
+		TimestampUnit: o.timestampUnit,
+		userAgent:     o.userAgent,
+		timeouts:      o.timeouts,
+		middleware:    o.middleware,
+	}, nil
+}
+
+// transport builds the default transport from the options
+func (o *clientOptions) transport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   defaultDialTimeout,
+		KeepAlive: defaultKeepAlive,
+	}
+	transport := &http.Transport{
+		Proxy:                 o.proxy,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          o.maxIdleConns,
+		MaxIdleConnsPerHost:   defaultMaxIdleConnsPerHost,
+		IdleConnTimeout:       defaultIdleConnTimeout,
+		TLSHandshakeTimeout:   defaultTLSHandshakeTimeout,
+		ExpectContinueTimeout: time.Second,
+		TLSClientConfig:       o.tlsConfig,
+	}
+	if o.maxIdleConns > 0 && o.maxIdleConns < defaultMaxIdleConnsPerHost {
+		transport.MaxIdleConnsPerHost = o.maxIdleConns
+	}
+	if o.unixSocket != "" {
+		socket := o.unixSocket
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+	}
+	return transport
+}
@@ -0,0 +1,109 @@
+package rls
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewDefaults(t *testing.T) {
+	var userAgent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client, err := New(*NewConfig(srv.URL, "key", "acct", "", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.HTTPClient.Timeout != DefaultTimeout || client.RetryPolicy == nil {
+		t.Errorf("unexpected defaults: timeout %s, retry policy %v", client.HTTPClient.Timeout, client.RetryPolicy)
+	}
+	if _, err := client.GetAccountContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if userAgent != DefaultUserAgent {
+		t.Errorf("got User-Agent %q", userAgent)
+	}
+}
+
+func TestNewOptionErrors(t *testing.T) {
+	cfg := *NewConfig("http://localhost", "key", "acct", "", nil)
+	for name, opts := range map[string][]Option{
+		"negative timeout":        {WithTimeout(-time.Second)},
+		"negative op timeouts":    {WithPerOperationTimeouts(-1, 0)},
+		"nil http client":         {WithHTTPClient(nil)},
+		"proxy without host":      {WithProxy("localhost")},
+		"empty unix socket":       {WithUnixSocket("")},
+		"http client and tuning":  {WithHTTPClient(http.DefaultClient), WithMaxIdleConns(5)},
+		"negative max idle conns": {WithMaxIdleConns(-1)},
+		"invalid timestamp unit":  {WithTimestampUnit(TimestampMilliseconds + 1)},
+	} {
+		if _, err := New(cfg, opts...); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestPerOperationTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	client, err := New(*NewConfig(srv.URL, "key", "acct", "", nil),
+		WithPerOperationTimeouts(50*time.Millisecond, 0),
+		WithRetryPolicy(nil),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := client.GetAccountContext(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("read timeout not applied")
+	}
+}
+
+func TestOperationTimeouts(t *testing.T) {
+	timeouts := operationTimeouts{read: time.Second, write: time.Minute}
+	for _, op := range []string{OpNewWithdrawal, OpNewInvoice, OpSubscribeToWebhook, OpDeleteWebhook} {
+		if got := timeouts.forOperation(op); got != time.Minute {
+			t.Errorf("%s: got %s, want the write timeout", op, got)
+		}
+	}
+	for _, op := range []string{OpGetAccount, OpGetWithdrawal, OpListWithdrawals, OpDecodeInvoice, OpEstimateLightningFee} {
+		if got := timeouts.forOperation(op); got != time.Second {
+			t.Errorf("%s: got %s, want the read timeout", op, got)
+		}
+	}
+}
+
+func TestWithUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rls.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	srv := &httptest.Server{Listener: listener, Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"wd_1"}`))
+	})}}
+	srv.Start()
+	defer srv.Close()
+
+	client, err := New(*NewConfig("http://rls", "key", "acct", "", nil), WithUnixSocket(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wd, err := client.GetWithdrawalContext(context.Background(), "wd_1"); err != nil || wd.ID != "wd_1" {
+		t.Fatalf("got %v, %v", wd, err)
+	}
+}
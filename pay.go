@@ -0,0 +1,129 @@
+package rls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrAmountMismatch is returned by Pay when the amount given does not match the invoice's amount
+	ErrAmountMismatch = errors.New("rls: amount does not match invoice")
+	// ErrAmountRequired is returned by Pay when neither the invoice nor the options specify an amount
+	ErrAmountRequired = errors.New("rls: amount required for amountless invoice")
+	// ErrFeeLimitExceeded is returned by Pay when the estimated fee is above the fee limit
+	ErrFeeLimitExceeded = errors.New("rls: estimated fee exceeds fee limit")
+)
+
+// PayOptions configures Pay
+type PayOptions struct {
+	// Amount to pay. It is required for amountless invoices, and must be 0 or match the invoice otherwise
+	Amount Amount
+	// FeePolicy chooses the fee limit from the amount. Defaults to DefaultFeePolicy.
+	// Use AbsoluteFee for a fixed limit, including a limit of 0.
+	FeePolicy FeePolicy
+	// Currency of the balance to pay from. Defaults to BTC, the only currency supported: invoice amounts
+	// and fees are in BTC, so paying from another balance fails with ErrUnsupportedCurrency
+	Currency string
+	// IdempotencyKey of the withdrawal. It is generated if empty. Calling Pay again with the same key never pays twice
+	IdempotencyKey string
+	// Confirm, if set, is called once every check has passed, before the withdrawal is submitted.
+	// Returning an error aborts the payment.
+	Confirm func(*PaymentResult) error
+	// Wait waits for the withdrawal to succeed or fail, with WaitOptions
+	Wait        bool
+	WaitOptions WaitOptions
+}
+
+// PaymentResult holds the data gathered by every step of Pay. Steps that did not run are nil.
+type PaymentResult struct {
+	// Invoice is the decoded invoice
+	Invoice *DecodedInvoice
+	// Amount and FeeLimit are the amount paid and fee limit used
+	Amount   Amount
+	FeeLimit Amount
+	// Account is the account as checked before paying
+	Account *Account
+	// FeeEstimate is the fee estimated by RLS
+	FeeEstimate *FeeEstimate
+	// Withdrawal is the submitted withdrawal, in its final state if Pay waited for it
+	Withdrawal *Withdrawal
+}
+
+// Pay pays invoice after checking it can succeed: it decodes the invoice, checks or fills in the amount,
+// checks the available balance covers the amount and fee limit, and refuses to pay if the estimated fee
+// exceeds the fee limit. The withdrawal is submitted with an idempotency key and recovered if the
+// submission fails ambiguously, then optionally waited for. The result is returned even on error,
+// with the steps that completed.
+func (rls *RLSClient) Pay(ctx context.Context, invoice string, opts PayOptions) (*PaymentResult, error) {
+	result := &PaymentResult{}
+	if opts.Currency != "" && !strings.EqualFold(opts.Currency, CurrencyBTC) {
+		return result, fmt.Errorf("failed to pay : %w : %s", ErrUnsupportedCurrency, opts.Currency)
+	}
+
+	decoded, err := rls.DecodeInvoiceContext(ctx, invoice)
+	if err != nil {
+		return result, fmt.Errorf("failed to pay : %w", err)
+	}
+	result.Invoice = decoded
+	switch {
+	case decoded.Amount == 0 && opts.Amount == 0:
+		return result, fmt.Errorf("failed to pay : %w", ErrAmountRequired)
+	case decoded.Amount == 0:
+		result.Amount = opts.Amount
+	case opts.Amount != 0 && opts.Amount != decoded.Amount:
+		return result, fmt.Errorf("failed to pay : %w : invoice is for %s, not %s", ErrAmountMismatch, decoded.Amount, opts.Amount)
+	default:
+		result.Amount = decoded.Amount
+	}
+	policy := opts.FeePolicy
+	if policy == nil {
+		policy = DefaultFeePolicy
+	}
+	result.FeeLimit = policy.FeeLimit(result.Amount)
+
+	acct, err := rls.GetAccountContext(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to pay : %w", err)
+	}
+	result.Account = acct
+	if required := result.Amount + result.FeeLimit; acct.AvailableBalance < required {
+		return result, fmt.Errorf("failed to pay : %w : %s available, %s required with fee limit", ErrInsufficientFunds, acct.AvailableBalance, required)
+	}
+
+	estimate, err := rls.EstimateLightningFeeContext(ctx, invoice, result.Amount)
+	if err != nil {
+		return result, fmt.Errorf("failed to pay : %w", err)
+	}
+	result.FeeEstimate = estimate
+	if estimate.Fee > result.FeeLimit {
+		return result, fmt.Errorf("failed to pay : %w : estimated %s, limit %s", ErrFeeLimitExceeded, estimate.Fee, result.FeeLimit)
+	}
+	if opts.Confirm != nil {
+		if err := opts.Confirm(result); err != nil {
+			return result, fmt.Errorf("failed to pay : %w", err)
+		}
+	}
+
+	withdrawal := NewWithdrawalFromAmount(result.Amount, invoice, result.FeeLimit)
+	withdrawal.IdempotencyKey = opts.IdempotencyKey
+	wd, err := rls.NewWithdrawalWithRecovery(ctx, withdrawal, DefaultRecoveryAttempts)
+	if err != nil {
+		return result, fmt.Errorf("failed to pay : %w", err)
+	}
+	result.Withdrawal = wd
+	if !opts.Wait {
+		return result, nil
+	}
+
+	final, err := rls.WaitForWithdrawal(ctx, wd.ID, opts.WaitOptions)
+	if final != nil {
+		final.IdempotencyKey = wd.IdempotencyKey
+		result.Withdrawal = final
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to pay : %w", err)
+	}
+	return result, nil
+}
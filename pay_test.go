@@ -0,0 +1,135 @@
+package rls
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// payServer is an RLS fake serving the endpoints used by Pay, with amounts in sats
+type payServer struct {
+	invoiceAmount int64
+	available     int64
+	fee           int64
+	requests      int
+	submitted     []Withdrawal
+}
+
+func (s *payServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests++
+	switch r.URL.Path {
+	case "/lightning/parse_invoice":
+		fmt.Fprintf(w, `{"amount":%d,"destination":"lnbc"}`, s.invoiceAmount)
+	case "/accounts/acct":
+		fmt.Fprintf(w, `{"id":"acct","balance":%d,"available_balance":%d}`, s.available, s.available)
+	case "/lightning/estimate_fee":
+		fmt.Fprintf(w, `{"destination":"lnbc","fee":%d}`, s.fee)
+	case "/accounts/acct/withdrawals":
+		var wd Withdrawal
+		if err := json.NewDecoder(r.Body).Decode(&wd); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.submitted = append(s.submitted, wd)
+		wd.ID, wd.State = "wd_1", WithdrawalStatePending
+		_ = json.NewEncoder(w).Encode(wd)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestPay(t *testing.T) {
+	srv := &payServer{invoiceAmount: 1000, available: 2000, fee: 3}
+	client := newTestClient(t, srv.ServeHTTP)
+	var confirmed *PaymentResult
+	result, err := client.Pay(context.Background(), "lnbc", PayOptions{
+		FeePolicy:      PercentFee(1),
+		IdempotencyKey: "key",
+		Confirm: func(result *PaymentResult) error {
+			confirmed = result
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Amount != Sats(1000) || result.FeeLimit != Sats(10) || result.FeeEstimate.Fee != Sats(3) || confirmed != result {
+		t.Errorf("got %+v", result)
+	}
+	if len(srv.submitted) != 1 {
+		t.Fatalf("got %d submissions", len(srv.submitted))
+	}
+	if wd := srv.submitted[0]; wd.Amount != Sats(1000) || wd.Currency != BTC || wd.Details.FeeLimit != Sats(10) {
+		t.Errorf("submitted %+v", wd)
+	}
+	if result.Withdrawal.ID != "wd_1" || result.Withdrawal.IdempotencyKey != "key" {
+		t.Errorf("got withdrawal %+v", result.Withdrawal)
+	}
+}
+
+func TestPayChecks(t *testing.T) {
+	abort := errors.New("aborted")
+	tests := []struct {
+		name     string
+		srv      payServer
+		opts     PayOptions
+		sentinel error
+	}{
+		{"amountless invoice", payServer{available: 2000}, PayOptions{}, ErrAmountRequired},
+		{"amount mismatch", payServer{invoiceAmount: 1000, available: 2000}, PayOptions{Amount: Sats(999)}, ErrAmountMismatch},
+		{"insufficient funds", payServer{invoiceAmount: 1000, available: 1000}, PayOptions{}, ErrInsufficientFunds},
+		{"fee above limit", payServer{invoiceAmount: 1000, available: 2000, fee: 11}, PayOptions{FeePolicy: AbsoluteFee(Sats(10))}, ErrFeeLimitExceeded},
+		{"fee above zero limit", payServer{invoiceAmount: 1000, available: 2000, fee: 1}, PayOptions{FeePolicy: AbsoluteFee(0)}, ErrFeeLimitExceeded},
+		{"confirmation refused", payServer{invoiceAmount: 1000, available: 2000}, PayOptions{Confirm: func(*PaymentResult) error { return abort }}, abort},
+		{"non-BTC currency", payServer{invoiceAmount: 1000, available: 2000}, PayOptions{Currency: "USD"}, ErrUnsupportedCurrency},
+		{"sub-sat fee limit", payServer{invoiceAmount: 1000, available: 2000}, PayOptions{FeePolicy: FeePolicyFunc(func(Amount) Amount { return MSats(1500) })}, ErrInvalidRequest},
+	}
+	for _, tt := range tests {
+		srv := tt.srv
+		client := newTestClient(t, srv.ServeHTTP)
+		result, err := client.Pay(context.Background(), "lnbc", tt.opts)
+		if !errors.Is(err, tt.sentinel) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.sentinel, err)
+		}
+		if result == nil || result.Withdrawal != nil || len(srv.submitted) != 0 {
+			t.Errorf("%s: payment submitted", tt.name)
+		}
+	}
+
+	// a non-BTC currency is rejected before any request, as its balance is not comparable to the invoice
+	srv := &payServer{invoiceAmount: 1000, available: 2000}
+	client := newTestClient(t, srv.ServeHTTP)
+	if _, err := client.Pay(context.Background(), "lnbc", PayOptions{Currency: "usd"}); !errors.Is(err, ErrUnsupportedCurrency) || srv.requests != 0 {
+		t.Errorf("got %v after %d requests", err, srv.requests)
+	}
+	if _, err := client.Pay(context.Background(), "lnbc", PayOptions{Currency: "btc"}); err != nil {
+		t.Errorf("lowercase BTC rejected: %v", err)
+	}
+}
+
+func TestPayZeroFeeLimit(t *testing.T) {
+	srv := &payServer{invoiceAmount: 1000, available: 1000}
+	client := newTestClient(t, srv.ServeHTTP)
+	result, err := client.Pay(context.Background(), "lnbc", PayOptions{FeePolicy: AbsoluteFee(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FeeLimit != 0 || len(srv.submitted) != 1 || srv.submitted[0].Details.FeeLimit != 0 {
+		t.Errorf("got fee limit %s, submitted %+v", result.FeeLimit, srv.submitted)
+	}
+}
+
+func TestPaySubSatFeeLimit(t *testing.T) {
+	srv := &payServer{invoiceAmount: 1000, available: 2000}
+	client := newTestClient(t, srv.ServeHTTP)
+	result, err := client.Pay(context.Background(), "lnbc", PayOptions{FeePolicy: AbsoluteFee(MSats(1500))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FeeLimit != Sats(1) || len(srv.submitted) != 1 || srv.submitted[0].Details.FeeLimit != Sats(1) {
+		t.Errorf("got fee limit %s, submitted %+v", result.FeeLimit, srv.submitted)
+	}
+}
@@ -0,0 +1,522 @@
+package rls
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Environment variables read by LoadConfig
+const (
+	// ConfigPathEnv is the environment variable holding the config file path
+	ConfigPathEnv = "RLS_CONFIG"
+	// ProfileEnv is the environment variable holding the profile to load
+	ProfileEnv = "RLS_PROFILE"
+	// DefaultProfileName is the profile loaded when none is selected
+	DefaultProfileName = "default"
+)
+
+// Suffixes of the override environment variables, appended to LoadOptions.EnvPrefix
+const (
+	EnvSuffixURL           = "_URL"
+	EnvSuffixAccountID     = "_RIVER_ACCOUNT_ID"
+	EnvSuffixAPISecret     = "_RIVER_API_SECRET"
+	EnvSuffixWebhookSecret = "_WEBHOOK_SECRET"
+	EnvSuffixHeaders       = "_HEADERS"
+	EnvSuffixTLSPath       = "_TLSPATH"
+)
+
+// configFileNames are the names looked up in the user config directory, in order
+var configFileNames = []string{"config.toml", "config.yaml", "config.yml", "config.json"}
+
+// ErrProfileNotFound is returned when the requested profile is not in the config file
+var ErrProfileNotFound = errors.New("rls: profile not found")
+
+// Profile is a named set of connection settings in a config file
+type Profile struct {
+	Name          string            `json:"-"`
+	BaseURL       string            `json:"base_url,omitempty"`
+	AccountID     string            `json:"account_id,omitempty"`
+	APIKey        string            `json:"api_key,omitempty"`
+	WebhookSecret string            `json:"webhook_secret,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	// APIKeyFile and APIKeyCommand read the API key from a file or a helper command when APIKey is not set
+	APIKeyFile    string `json:"api_key_file,omitempty"`
+	APIKeyCommand string `json:"api_key_command,omitempty"`
+	// WebhookSecretFile and WebhookSecretCommand read the webhook secret from a file or a helper
+	// command when WebhookSecret is not set
+	WebhookSecretFile    string `json:"webhook_secret_file,omitempty"`
+	WebhookSecretCommand string `json:"webhook_secret_command,omitempty"`
+	// TLSPath is the path of the client certificate and key, without the .cert and .key extensions
+	TLSPath string `json:"tls_path,omitempty"`
+	// TLSCertFile and TLSKeyFile are the client certificate and key, taking precedence over TLSPath
+	TLSCertFile string `json:"tls_cert_file,omitempty"`
+	TLSKeyFile  string `json:"tls_key_file,omitempty"`
+	// TLSCAFile is a PEM bundle of the CAs trusted to verify the server
+	TLSCAFile string `json:"tls_ca_file,omitempty"`
+	// TLSPins is a comma-separated list of pinned SPKI hashes of the server. See TLSOptions.PinnedSPKI
+	TLSPins string `json:"tls_pins,omitempty"`
+	// Sources records where each setting was loaded from, keyed by its file key, e.g. "base_url"
+	Sources map[string]string `json:"-"`
+}
+
+// ConfigFile is a config file holding named profiles
+type ConfigFile struct {
+	// Path is the file the config was read from
+	Path           string              `json:"-"`
+	DefaultProfile string              `json:"default_profile,omitempty"`
+	Profiles       map[string]*Profile `json:"profiles,omitempty"`
+}
+
+// LoadOptions configures LoadConfig and LoadProfile
+type LoadOptions struct {
+	// Path is the config file. Defaults to $RLS_CONFIG, then the first of config.toml, config.yaml,
+	// config.yml and config.json found in DefaultConfigDir. A missing default file is not an error.
+	Path string
+	// Profile is the profile to load. Defaults to $RLS_PROFILE, then the file's default_profile,
+	// then DefaultProfileName
+	Profile string
+	// EnvPrefix is prepended to the EnvSuffix* override variables, e.g. "RLS" reads RLS_URL.
+	// If empty, the un-prefixed variables such as _URL are read, as rlscli does when $RLS_ENV is unset
+	EnvPrefix string
+	// LookupEnv reads environment variables. Defaults to os.LookupEnv
+	LookupEnv func(key string) (string, bool)
+	// Keystore is consulted for secrets the profile does not otherwise provide, under the names
+	// KeystoreAPIKeyName and KeystoreWebhookSecretName. Defaults to the keystore at DefaultKeystorePath
+	Keystore *Keystore
+	// Passphrase returns the keystore passphrase. The keystore is only used if it is set
+	Passphrase func() ([]byte, error)
+	// Context bounds secret helper commands. Defaults to context.Background()
+	Context context.Context
+}
+
+// getenv returns the environment variable key, or "" if it is not set
+func (opts LoadOptions) getenv(key string) string {
+	lookupEnv := opts.LookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+	value, _ := lookupEnv(key)
+	return value
+}
+
+// KeystoreAPIKeyName returns the keystore entry holding the API key of profile
+func KeystoreAPIKeyName(profile string) string {
+	return profile + "/api_key"
+}
+
+// KeystoreWebhookSecretName returns the keystore entry holding the webhook secret of profile
+func KeystoreWebhookSecretName(profile string) string {
+	return profile + "/webhook_secret"
+}
+
+// DefaultConfigDir returns the directory holding the default config file, e.g. ~/.config/rls
+func DefaultConfigDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rls"), nil
+}
+
+// ReadConfigFile reads the config file at path. The format is chosen by the extension:
+// .json, .toml, .yaml or .yml.
+func ReadConfigFile(path string) (*ConfigFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file : %w", err)
+	}
+
+	var tree map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &tree)
+	case ".toml":
+		tree, err = parseTOML(data)
+	case ".yaml", ".yml":
+		tree, err = parseYAML(data)
+	default:
+		return nil, fmt.Errorf("failed to read config file %s : unsupported format %q", path, filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s : %w", path, err)
+	}
+
+	// round trip through JSON so every format decodes with the same struct tags
+	b, err := json.Marshal(tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s : %w", path, err)
+	}
+	file := ConfigFile{Path: path}
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s : %w", path, err)
+	}
+	for name, profile := range file.Profiles {
+		if profile == nil {
+			profile = &Profile{}
+			file.Profiles[name] = profile
+		}
+		profile.Name = name
+	}
+	return &file, nil
+}
+
+// Names returns the names of the profiles in the file, sorted
+func (f *ConfigFile) Names() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns a copy of the profile name, with Sources pointing at the file
+func (f *ConfigFile) Profile(name string) (*Profile, error) {
+	profile, ok := f.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w : %s in %s", ErrProfileNotFound, name, f.Path)
+	}
+	p := *profile
+	p.Headers = copyHeaders(profile.Headers)
+	p.Sources = make(map[string]string)
+	source := fmt.Sprintf("profile %s in %s", name, f.Path)
+	for key, value := range map[string]string{
+		"base_url":       p.BaseURL,
+		"account_id":     p.AccountID,
+		"api_key":        p.APIKey,
+		"webhook_secret": p.WebhookSecret,
+		"tls_path":       p.TLSPath,
+		"tls_cert_file":  p.TLSCertFile,
+		"tls_key_file":   p.TLSKeyFile,
+		"tls_ca_file":    p.TLSCAFile,
+		"tls_pins":       p.TLSPins,
+
+		"api_key_file":           p.APIKeyFile,
+		"api_key_command":        p.APIKeyCommand,
+		"webhook_secret_file":    p.WebhookSecretFile,
+		"webhook_secret_command": p.WebhookSecretCommand,
+	} {
+		if value != "" {
+			p.Sources[key] = source
+		}
+	}
+	if len(p.Headers) > 0 {
+		p.Sources["headers"] = source
+	}
+	return &p, nil
+}
+
+// Use makes name the default profile and writes it back to the file, keeping the rest of the file as is
+func (f *ConfigFile) Use(name string) error {
+	if _, ok := f.Profiles[name]; !ok {
+		return fmt.Errorf("%w : %s in %s", ErrProfileNotFound, name, f.Path)
+	}
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return fmt.Errorf("failed to update config file : %w", err)
+	}
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return fmt.Errorf("failed to update config file : %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(f.Path)) {
+	case ".json":
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("failed to update config file : %w", err)
+		}
+		raw["default_profile"], _ = json.Marshal(name)
+		if data, err = json.MarshalIndent(raw, "", "  "); err != nil {
+			return fmt.Errorf("failed to update config file : %w", err)
+		}
+		data = append(data, '\n')
+	case ".toml":
+		data = setTopLevelLine(data, tomlDefaultProfilePattern, "default_profile = "+strconv.Quote(name))
+	default:
+		data = setTopLevelLine(data, yamlDefaultProfilePattern, "default_profile: "+strconv.Quote(name))
+	}
+
+	if err := os.WriteFile(f.Path, data, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to update config file : %w", err)
+	}
+	f.DefaultProfile = name
+	return nil
+}
+
+var (
+	tomlDefaultProfilePattern = regexp.MustCompile(`^\s*default_profile\s*=`)
+	yamlDefaultProfilePattern = regexp.MustCompile(`^default_profile\s*:`)
+)
+
+// setTopLevelLine replaces the first top-level line matching pattern with line, or inserts line at the top.
+// In TOML, lines after the first table header are not top-level.
+func setTopLevelLine(data []byte, pattern *regexp.Regexp, line string) []byte {
+	lines := strings.Split(string(data), "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(strings.TrimSpace(l), "[") {
+			break
+		}
+		if pattern.MatchString(l) {
+			lines[i] = line
+			return []byte(strings.Join(lines, "\n"))
+		}
+	}
+	return []byte(line + "\n" + strings.Join(lines, "\n"))
+}
+
+// Validate checks that the profile has a valid base URL, account ID and API key,
+// and that the webhook secret, if set, is hex encoded
+func (p *Profile) Validate() error {
+	var problems []string
+	if p.BaseURL == "" {
+		problems = append(problems, "base URL is not set")
+	} else if u, err := url.Parse(p.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems = append(problems, fmt.Sprintf("base URL %q is not an http(s) URL", p.BaseURL))
+	}
+	if p.AccountID == "" {
+		problems = append(problems, "account ID is not set")
+	}
+	if p.APIKey == "" {
+		problems = append(problems, "API secret is not set")
+	}
+	if p.WebhookSecret != "" {
+		if _, err := hex.DecodeString(p.WebhookSecret); err != nil {
+			problems = append(problems, "webhook secret is not hex encoded")
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid profile %s : %s", p.Name, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Config returns the Config of the profile
+func (p *Profile) Config() *Config {
+	return NewConfig(p.BaseURL, p.APIKey, p.AccountID, p.WebhookSecret, copyHeaders(p.Headers))
+}
+
+// ProfileName returns the name of the profile LoadProfile would load with opts
+func ProfileName(opts LoadOptions) (string, error) {
+	file, err := findConfigFile(opts.Path, opts.getenv)
+	if err != nil {
+		return "", err
+	}
+	name, _ := selectProfile(opts, file)
+	return name, nil
+}
+
+// selectProfile returns the name of the profile to load and whether it was explicitly requested
+func selectProfile(opts LoadOptions, file *ConfigFile) (string, bool) {
+	switch {
+	case opts.Profile != "":
+		return opts.Profile, true
+	case opts.getenv(ProfileEnv) != "":
+		return opts.getenv(ProfileEnv), true
+	case file != nil && file.DefaultProfile != "":
+		return file.DefaultProfile, true
+	default:
+		return DefaultProfileName, false
+	}
+}
+
+// TLSOptions returns the TLS options of the profile
+func (p *Profile) TLSOptions() TLSOptions {
+	opts := TLSOptions{
+		CertFile: p.TLSCertFile,
+		KeyFile:  p.TLSKeyFile,
+		CAFile:   p.TLSCAFile,
+	}
+	if opts.CertFile == "" && opts.KeyFile == "" && p.TLSPath != "" {
+		opts.CertFile, opts.KeyFile = TLSPathFiles(p.TLSPath)
+	}
+	for _, pin := range strings.Split(p.TLSPins, ",") {
+		if pin = strings.TrimSpace(pin); pin != "" {
+			opts.PinnedSPKI = append(opts.PinnedSPKI, pin)
+		}
+	}
+	return opts
+}
+
+// LoadProfile resolves a profile from the config file and the environment, and validates it.
+// Settings are taken, from highest to lowest precedence, from:
+//  1. the <EnvPrefix>_URL, _RIVER_ACCOUNT_ID, _RIVER_API_SECRET, _WEBHOOK_SECRET, _HEADERS and _TLSPATH
+//     environment variables, when non-empty. _HEADERS is merged into the profile's headers.
+//  2. the selected profile of the config file
+//  3. for the API key and webhook secret only: the profile's *_file, then *_command settings,
+//     then the keystore if opts.Passphrase is set
+func LoadProfile(opts LoadOptions) (*Profile, error) {
+	file, err := findConfigFile(opts.Path, opts.getenv)
+	if err != nil {
+		return nil, err
+	}
+
+	name, explicit := selectProfile(opts, file)
+	profile := &Profile{Name: name, Sources: make(map[string]string)}
+	if file != nil {
+		if p, err := file.Profile(name); err == nil {
+			profile = p
+		} else if explicit {
+			return nil, err
+		}
+	} else if explicit {
+		return nil, fmt.Errorf("%w : %s (no config file)", ErrProfileNotFound, name)
+	}
+
+	prefix := opts.EnvPrefix
+	for _, override := range []struct {
+		key, suffix string
+		field       *string
+	}{
+		{"base_url", EnvSuffixURL, &profile.BaseURL},
+		{"account_id", EnvSuffixAccountID, &profile.AccountID},
+		{"api_key", EnvSuffixAPISecret, &profile.APIKey},
+		{"webhook_secret", EnvSuffixWebhookSecret, &profile.WebhookSecret},
+		{"tls_path", EnvSuffixTLSPath, &profile.TLSPath},
+	} {
+		if value := opts.getenv(prefix + override.suffix); value != "" {
+			*override.field = value
+			profile.Sources[override.key] = "env " + prefix + override.suffix
+		}
+	}
+	if headers := opts.getenv(prefix + EnvSuffixHeaders); headers != "" {
+		if profile.Headers == nil {
+			profile.Headers = make(map[string]string)
+		}
+		for key, value := range parseHeaderList(headers) {
+			profile.Headers[key] = value
+		}
+		profile.Sources["headers"] = "env " + prefix + EnvSuffixHeaders
+	}
+
+	if err := profile.loadSecrets(opts); err != nil {
+		return nil, err
+	}
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// loadSecrets fills the API key and webhook secret from their files, commands or the keystore when they are not set
+func (p *Profile) loadSecrets(opts LoadOptions) error {
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	keystore := opts.Keystore
+	if keystore == nil && opts.Passphrase != nil {
+		path, err := DefaultKeystorePath()
+		if err == nil {
+			keystore = OpenKeystore(path)
+		}
+	}
+
+	for _, secret := range []struct {
+		key, file, command, entry string
+		field                     *string
+	}{
+		{"api_key", p.APIKeyFile, p.APIKeyCommand, KeystoreAPIKeyName(p.Name), &p.APIKey},
+		{"webhook_secret", p.WebhookSecretFile, p.WebhookSecretCommand, KeystoreWebhookSecretName(p.Name), &p.WebhookSecret},
+	} {
+		if *secret.field != "" {
+			continue
+		}
+		var source SecretSource
+		switch {
+		case secret.file != "":
+			source = FileSecret(secret.file)
+			p.Sources[secret.key] = "file " + secret.file
+		case secret.command != "":
+			source = CommandSecret(secret.command)
+			p.Sources[secret.key] = "command"
+		case keystore != nil && opts.Passphrase != nil:
+			ok, err := keystore.Has(secret.entry)
+			if err != nil {
+				return fmt.Errorf("failed to load %s of profile %s : %w", secret.key, p.Name, err)
+			}
+			if !ok {
+				continue
+			}
+			source = KeystoreSecret(keystore, secret.entry, opts.Passphrase)
+			p.Sources[secret.key] = "keystore " + keystore.Path
+		default:
+			continue
+		}
+		value, err := source.Secret(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to load %s of profile %s : %w", secret.key, p.Name, err)
+		}
+		*secret.field = value
+	}
+	return nil
+}
+
+// LoadConfig resolves a profile with LoadProfile and returns its Config
+func LoadConfig(opts LoadOptions) (*Config, error) {
+	profile, err := LoadProfile(opts)
+	if err != nil {
+		return nil, err
+	}
+	return profile.Config(), nil
+}
+
+// FindConfigFile returns the config file LoadProfile would read with opts, or nil if there is none
+func FindConfigFile(opts LoadOptions) (*ConfigFile, error) {
+	return findConfigFile(opts.Path, opts.getenv)
+}
+
+// findConfigFile reads path, or $RLS_CONFIG, or the first default config file that exists
+func findConfigFile(path string, getenv func(string) string) (*ConfigFile, error) {
+	if path == "" {
+		path = getenv(ConfigPathEnv)
+	}
+	if path != "" {
+		return ReadConfigFile(path)
+	}
+
+	dir, err := DefaultConfigDir()
+	if err != nil {
+		return nil, nil
+	}
+	for _, name := range configFileNames {
+		candidate := filepath.Join(dir, name)
+		if _, err := os.Stat(candidate); err == nil {
+			return ReadConfigFile(candidate)
+		}
+	}
+	return nil, nil
+}
+
+// parseHeaderList parses headers in the format key:value,key:value
+func parseHeaderList(s string) map[string]string {
+	headers := make(map[string]string)
+	for _, header := range strings.Split(s, ",") {
+		kv := strings.SplitN(header, ":", 2)
+		if len(kv) == 2 && strings.TrimSpace(kv[0]) != "" {
+			headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	return headers
+}
+
+func copyHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(headers))
+	for key, value := range headers {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,120 @@
+package rls
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeConfig writes a config file named name with data to a temporary directory and returns its path
+func writeConfig(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// lookupEnv returns a LoadOptions.LookupEnv reading from env
+func lookupEnv(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+const testConfig = `
+default_profile = "sandbox"
+
+[profiles.sandbox]
+base_url = "https://sandbox.example.com"
+account_id = "acct_sandbox"
+api_key = "sandbox_key"
+headers = { X-Team = "treasury" }
+
+[profiles.prod]
+base_url = "https://rls.example.com"
+account_id = "acct_prod"
+`
+
+func TestLoadProfile(t *testing.T) {
+	path := writeConfig(t, "config.toml", testConfig)
+	profile, err := LoadProfile(LoadOptions{Path: path, LookupEnv: lookupEnv(nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.Name != "sandbox" || profile.BaseURL != "https://sandbox.example.com" || profile.Headers["X-Team"] != "treasury" {
+		t.Errorf("got %+v", profile)
+	}
+	if profile.Sources["api_key"] != "profile sandbox in "+path {
+		t.Errorf("got sources %v", profile.Sources)
+	}
+
+	if _, err := LoadProfile(LoadOptions{Path: path, Profile: "missing", LookupEnv: lookupEnv(nil)}); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("expected a missing profile error, got %v", err)
+	}
+	if _, err := LoadProfile(LoadOptions{Path: path, Profile: "prod", LookupEnv: lookupEnv(nil)}); err == nil {
+		t.Error("expected a validation error for a profile without an API key")
+	}
+}
+
+func TestLoadProfileEnvPrefix(t *testing.T) {
+	path := writeConfig(t, "config.toml", testConfig)
+	env := map[string]string{
+		"_URL":                     "https://unprefixed.example.com",
+		"_HEADERS":                 "X-Env:1",
+		"RLS_URL":                  "https://rls-prefixed.example.com",
+		"SANDBOX_URL":              "https://sandbox-prefixed.example.com",
+		"SANDBOX_RIVER_API_SECRET": "env_key",
+	}
+	tests := []struct {
+		prefix, url, key string
+	}{
+		{"", "https://unprefixed.example.com", "sandbox_key"},
+		{"RLS", "https://rls-prefixed.example.com", "sandbox_key"},
+		{"SANDBOX", "https://sandbox-prefixed.example.com", "env_key"},
+	}
+	for _, tt := range tests {
+		profile, err := LoadProfile(LoadOptions{Path: path, EnvPrefix: tt.prefix, LookupEnv: lookupEnv(env)})
+		if err != nil {
+			t.Fatalf("prefix %q: %v", tt.prefix, err)
+		}
+		if profile.BaseURL != tt.url || profile.APIKey != tt.key {
+			t.Errorf("prefix %q: got %s, %s", tt.prefix, profile.BaseURL, profile.APIKey)
+		}
+		if profile.Sources["base_url"] != "env "+tt.prefix+EnvSuffixURL {
+			t.Errorf("prefix %q: got source %q", tt.prefix, profile.Sources["base_url"])
+		}
+	}
+
+	profile, err := LoadProfile(LoadOptions{Path: path, LookupEnv: lookupEnv(env)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.Headers["X-Team"] != "treasury" || profile.Headers["X-Env"] != "1" {
+		t.Errorf("env headers not merged: %v", profile.Headers)
+	}
+}
+
+func TestLoadProfileFromEnvOnly(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	env := map[string]string{
+		"_URL":              "https://rls.example.com",
+		"_RIVER_ACCOUNT_ID": "acct",
+		"_RIVER_API_SECRET": "key",
+	}
+	profile, err := LoadProfile(LoadOptions{LookupEnv: lookupEnv(env)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.BaseURL != "https://rls.example.com" || profile.AccountID != "acct" || profile.APIKey != "key" {
+		t.Errorf("got %+v", profile)
+	}
+
+	if _, err := LoadProfile(LoadOptions{Path: filepath.Join(t.TempDir(), "missing.toml"), LookupEnv: lookupEnv(env)}); err == nil {
+		t.Error("expected an error for a missing explicit config file")
+	}
+}
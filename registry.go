@@ -0,0 +1,158 @@
+package rls
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// WithConfig returns a client for cfg that shares the HTTP client, transport, retry policy, timeouts
+// and middleware of rls. Middleware added to the returned client with Use does not affect rls.
+func (rls *RLSClient) WithConfig(cfg Config) *RLSClient {
+	view := *rls
+	view.cfg = cfg
+	view.middleware = append([]Middleware(nil), rls.middleware...)
+	return &view
+}
+
+// ForAccount returns a lightweight client for accountID using the same credentials and transport as rls
+func (rls *RLSClient) ForAccount(accountID string) *RLSClient {
+	cfg := rls.cfg
+	cfg.AccountID = accountID
+	return rls.WithConfig(cfg)
+}
+
+// Registry holds clients for several RLS accounts, keyed by a name of the caller's choosing.
+// Each account has its own credentials, while all clients created by Register share one transport.
+// A Registry is safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	opts    []Option
+	base    *RLSClient
+	clients map[string]*RLSClient
+}
+
+// NewRegistry returns an empty Registry. opts are used to create the shared transport on the first Register.
+func NewRegistry(opts ...Option) *Registry {
+	return &Registry{
+		opts:    opts,
+		clients: make(map[string]*RLSClient),
+	}
+}
+
+// Register adds the account configured by cfg under name, replacing any account with the same name
+func (r *Registry) Register(name string, cfg Config) (*RLSClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var client *RLSClient
+	if r.base == nil {
+		base, err := New(cfg, r.opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register account %s : %w", name, err)
+		}
+		r.base, client = base, base
+	} else {
+		client = r.base.WithConfig(cfg)
+	}
+	r.clients[name] = client
+	return client, nil
+}
+
+// Add adds an existing client under name, replacing any account with the same name
+func (r *Registry) Add(name string, client *RLSClient) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients[name] = client
+}
+
+// Remove removes the account registered under name
+func (r *Registry) Remove(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.clients, name)
+}
+
+// Get returns the client registered under name
+func (r *Registry) Get(name string) (*RLSClient, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	client, ok := r.clients[name]
+	return client, ok
+}
+
+// Names returns the names of the registered accounts, sorted
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.clients))
+	for name := range r.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Accounts fetches every registered account concurrently, keyed by name. If some accounts
+// fail, the accounts that succeeded are returned along with the first error by name.
+func (r *Registry) Accounts(ctx context.Context) (map[string]*Account, error) {
+	names := r.Names()
+	accounts := make([]*Account, len(names))
+	errs := make([]error, len(names))
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		client, ok := r.Get(name)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, client *RLSClient) {
+			defer wg.Done()
+			accounts[i], errs[i] = client.GetAccountContext(ctx)
+		}(i, client)
+	}
+	wg.Wait()
+
+	result := make(map[string]*Account, len(names))
+	var firstErr error
+	for i, name := range names {
+		if errs[i] != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to get account %s : %w", name, errs[i])
+			}
+			continue
+		}
+		if accounts[i] != nil {
+			result[name] = accounts[i]
+		}
+	}
+	return result, firstErr
+}
+
+// TotalBalance returns the sum of the balances of every registered account
+func (r *Registry) TotalBalance(ctx context.Context) (Amount, error) {
+	accounts, err := r.Accounts(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var total Amount
+	for _, acct := range accounts {
+		total += acct.Balance
+	}
+	return total, nil
+}
+
+// TotalAvailableBalance returns the sum of the available balances of every registered account
+func (r *Registry) TotalAvailableBalance(ctx context.Context) (Amount, error) {
+	accounts, err := r.Accounts(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var total Amount
+	for _, acct := range accounts {
+		total += acct.AvailableBalance
+	}
+	return total, nil
+}
@@ -0,0 +1,88 @@
+package rls
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// accountsServer serves GET /accounts/{id} with a balance of 1000 sats per account, failing for "bad"
+func accountsServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/accounts/")
+		if id == "bad" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"id":%q,"balance":1000,"available_balance":600}`, id)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRegistry(t *testing.T) {
+	srv := accountsServer(t)
+	registry := NewRegistry(WithRetryPolicy(nil))
+	a, err := registry.Register("a", *NewConfig(srv.URL, "key_a", "acct_a", "", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := registry.Register("b", *NewConfig(srv.URL, "key_b", "acct_b", "", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.HTTPClient != b.HTTPClient || b.Credential() == a.Credential() {
+		t.Error("clients should share a transport but not credentials")
+	}
+	if !reflect.DeepEqual(registry.Names(), []string{"a", "b"}) {
+		t.Errorf("got names %v", registry.Names())
+	}
+
+	total, err := registry.TotalBalance(context.Background())
+	if err != nil || total != Sats(2000) {
+		t.Errorf("got total %s, %v", total, err)
+	}
+	available, err := registry.TotalAvailableBalance(context.Background())
+	if err != nil || available != Sats(1200) {
+		t.Errorf("got available %s, %v", available, err)
+	}
+
+	registry.Remove("b")
+	if _, ok := registry.Get("b"); ok {
+		t.Error("removed account still registered")
+	}
+}
+
+func TestRegistryPartialFailure(t *testing.T) {
+	srv := accountsServer(t)
+	registry := NewRegistry(WithRetryPolicy(nil))
+	for _, id := range []string{"good", "bad"} {
+		if _, err := registry.Register(id, *NewConfig(srv.URL, "key", id, "", nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	accounts, err := registry.Accounts(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "bad") {
+		t.Errorf("expected an error naming the failed account, got %v", err)
+	}
+	if len(accounts) != 1 || accounts["good"].ID != "good" {
+		t.Errorf("got %v", accounts)
+	}
+}
+
+func TestForAccount(t *testing.T) {
+	client := NewRLSClient(context.Background(), *NewConfig("http://rls", "key", "acct", "", nil), nil)
+	client.Use(Hooks{}.Middleware())
+	other := client.ForAccount("other")
+	other.Use(Hooks{}.Middleware())
+	if other.AccountID() != "other" || client.AccountID() != "acct" || other.Credential() != client.Credential() {
+		t.Errorf("unexpected accounts %s, %s", client.AccountID(), other.AccountID())
+	}
+	if len(client.middleware) != 1 || len(other.middleware) != 2 {
+		t.Error("middleware added to the view leaked into the original client")
+	}
+}
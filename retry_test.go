@@ -0,0 +1,99 @@
+package rls
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fastRetryPolicy retries quickly so tests do not sleep
+func fastRetryPolicy() *RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = time.Millisecond
+	return policy
+}
+
+func TestRetryTransientStatus(t *testing.T) {
+	var calls int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id":"wd_1"}`))
+	})
+	client.RetryPolicy = fastRetryPolicy()
+	if wd, err := client.GetWithdrawalContext(context.Background(), "wd_1"); err != nil || wd.ID != "wd_1" {
+		t.Fatalf("got %v, %v", wd, err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	var calls int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	client.RetryPolicy = fastRetryPolicy()
+	if _, err := client.GetAccountContext(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestNoRetryWithoutIdempotencyKey(t *testing.T) {
+	var calls int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	client.RetryPolicy = fastRetryPolicy()
+	if _, err := client.SubscribeToWebhookContext(context.Background(), "https://example.com/hook"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if calls != 1 {
+		t.Errorf("expected a single attempt for a POST without idempotency key, got %d", calls)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := &RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond, 5: time.Second, 10: time.Second} {
+		if got := policy.Backoff(attempt); got != want {
+			t.Errorf("Backoff(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := policy.Backoff(2); got < 100*time.Millisecond || got > 200*time.Millisecond {
+			t.Fatalf("jittered backoff %s out of range", got)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	res := &http.Response{Header: http.Header{}}
+	if _, ok := retryAfter(res); ok {
+		t.Error("expected no Retry-After")
+	}
+	res.Header.Set("Retry-After", "3")
+	if d, ok := retryAfter(res); !ok || d != 3*time.Second {
+		t.Errorf("got %s, %v", d, ok)
+	}
+	res.Header.Set("Retry-After", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+	if d, ok := retryAfter(res); !ok || d != 0 {
+		t.Errorf("past date: got %s, %v", d, ok)
+	}
+	res.Header.Set("Retry-After", "soon")
+	if _, ok := retryAfter(res); ok {
+		t.Error("expected invalid Retry-After to be ignored")
+	}
+}
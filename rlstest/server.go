@@ -0,0 +1,868 @@
+// Package rlstest provides an in-memory simulator of the RLS API served over httptest,
+// for testing code built on the rls client without a River sandbox.
+package rlstest
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	b64 "encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SachinMeier/rls-client"
+)
+
+const (
+	// DefaultAccountID is the account served by NewServer
+	DefaultAccountID = "acct_rlstest"
+	// DefaultAPIKey is the API key accepted by NewServer
+	DefaultAPIKey = "rlstest_api_key"
+	// DefaultPageLimit is the page size used when a list call omits limit
+	DefaultPageLimit = 25
+	// MaxPageLimit is the largest page size accepted by list calls
+	MaxPageLimit = 100
+)
+
+// Options configures a Server
+type Options struct {
+	// AccountID is the only account served. Defaults to DefaultAccountID
+	AccountID string
+	// APIKey is the only API key accepted. Defaults to DefaultAPIKey
+	APIKey string
+	// WebhookSecret is the hex secret used to sign webhooks. Generated if empty
+	WebhookSecret string
+	// Balance is the starting balance of the account
+	Balance rls.Amount
+	// FeeEstimator returns the fee estimate and fee paid for a payment. Defaults to 0.1% with a minimum of 1 sat
+	FeeEstimator func(amount rls.Amount) rls.Amount
+	// Clock returns the current time. Defaults to time.Now
+	Clock func() time.Time
+}
+
+// Server is an in-memory RLS API simulator
+type Server struct {
+	*httptest.Server
+
+	accountID     string
+	apiKey        string
+	webhookSecret string
+	feeEstimator  func(amount rls.Amount) rls.Amount
+	clock         func() time.Time
+	webhookClient *http.Client
+
+	mu            sync.Mutex
+	balance       rls.Amount
+	onHold        rls.Amount
+	lastTimestamp int64
+	nextID        int
+	invoices      map[string]*rls.Invoice
+	deposits      map[string]*rls.Deposit
+	withdrawals   map[string]*rls.Withdrawal
+	payable       map[string]*rls.DecodedInvoice
+	idempotent    map[string]interface{}
+	webhook       *rls.Webhook
+}
+
+// NewServer starts a Server with the default options
+func NewServer() *Server {
+	return NewServerWithOptions(Options{})
+}
+
+// NewServerWithOptions starts a Server configured by opts. Call Close when done.
+func NewServerWithOptions(opts Options) *Server {
+	s := &Server{
+		accountID:     opts.AccountID,
+		apiKey:        opts.APIKey,
+		webhookSecret: opts.WebhookSecret,
+		feeEstimator:  opts.FeeEstimator,
+		clock:         opts.Clock,
+		webhookClient: &http.Client{Timeout: 10 * time.Second},
+		balance:       opts.Balance,
+		invoices:      make(map[string]*rls.Invoice),
+		deposits:      make(map[string]*rls.Deposit),
+		withdrawals:   make(map[string]*rls.Withdrawal),
+		payable:       make(map[string]*rls.DecodedInvoice),
+		idempotent:    make(map[string]interface{}),
+	}
+	if s.accountID == "" {
+		s.accountID = DefaultAccountID
+	}
+	if s.apiKey == "" {
+		s.apiKey = DefaultAPIKey
+	}
+	if s.webhookSecret == "" {
+		s.webhookSecret = hex.EncodeToString(randomBytes(32))
+	}
+	if s.feeEstimator == nil {
+		s.feeEstimator = defaultFeeEstimator
+	}
+	if s.clock == nil {
+		s.clock = time.Now
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// defaultFeeEstimator charges 0.1% of the amount, with a minimum of 1 sat
+func defaultFeeEstimator(amount rls.Amount) rls.Amount {
+	fee := rls.Sats(amount.Sats() / 1000)
+	if fee < rls.Satoshi {
+		fee = rls.Satoshi
+	}
+	return fee
+}
+
+// AccountID returns the ID of the simulated account
+func (s *Server) AccountID() string {
+	return s.accountID
+}
+
+// WebhookSecret returns the hex secret used to sign webhooks
+func (s *Server) WebhookSecret() string {
+	return s.webhookSecret
+}
+
+// Config returns a Config for a client of the simulated account
+func (s *Server) Config() *rls.Config {
+	return rls.NewConfig(s.URL, s.apiKey, s.accountID, s.webhookSecret, nil)
+}
+
+// Client returns a client of the simulated account, using the server's HTTP client.
+// It panics if opts are invalid.
+func (s *Server) Client(opts ...rls.Option) *rls.RLSClient {
+	client, err := rls.New(*s.Config(), append([]rls.Option{rls.WithHTTPClient(s.Server.Client())}, opts...)...)
+	if err != nil {
+		panic(err)
+	}
+	return client
+}
+
+// Admin hooks
+
+// SetBalance sets the account's total balance
+func (s *Server) SetBalance(balance rls.Amount) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.balance = balance
+}
+
+// NewPayableInvoice returns a BOLT-11-looking invoice that the simulator can decode, estimate and pay
+func (s *Server) NewPayableInvoice(amount rls.Amount, memo string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	invoice := s.newInvoiceString(amount)
+	s.payable[invoice] = &rls.DecodedInvoice{
+		Amount:  amount,
+		Memo:    memo,
+		NodeID:  "02" + hex.EncodeToString(randomBytes(32)),
+		Invoice: invoice,
+	}
+	return invoice
+}
+
+// SettleInvoice pays the deposit invoice invoiceID with amount, crediting the account and
+// emitting a DEPOSIT webhook if one is subscribed
+func (s *Server) SettleInvoice(ctx context.Context, invoiceID string, amount rls.Amount) (*rls.Deposit, error) {
+	s.mu.Lock()
+	invoice, ok := s.invoices[invoiceID]
+	if !ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("invoice %s not found", invoiceID)
+	}
+	deposit := &rls.Deposit{
+		ID:      s.newID("dep"),
+		Invoice: *invoice,
+		Amount:  amount,
+		Detail: rls.DepositDetail{
+			Network: invoice.Network,
+			Proof:   hex.EncodeToString(randomBytes(32)),
+		},
+		State:     rls.DepositStateSuccess,
+		Timestamp: s.now(),
+	}
+	s.deposits[deposit.ID] = deposit
+	s.balance += amount
+	s.mu.Unlock()
+
+	err := s.emitIfSubscribed(ctx, rls.WebhookEvent{ID: deposit.ID, Type: rls.WebhookTypeDeposit, State: string(deposit.State)})
+	return copyDeposit(deposit), err
+}
+
+// SucceedWithdrawal completes the pending withdrawal withdrawalID, debiting its amount and the
+// simulated fee, and emits a WITHDRAWAL webhook if one is subscribed
+func (s *Server) SucceedWithdrawal(ctx context.Context, withdrawalID string) (*rls.Withdrawal, error) {
+	return s.finishWithdrawal(ctx, withdrawalID, rls.WithdrawalStateSuccess)
+}
+
+// FailWithdrawal fails the pending withdrawal withdrawalID, releasing its funds, and emits a
+// WITHDRAWAL webhook if one is subscribed
+func (s *Server) FailWithdrawal(ctx context.Context, withdrawalID string) (*rls.Withdrawal, error) {
+	return s.finishWithdrawal(ctx, withdrawalID, rls.WithdrawalStateFail)
+}
+
+func (s *Server) finishWithdrawal(ctx context.Context, withdrawalID string, state rls.WithdrawalState) (*rls.Withdrawal, error) {
+	s.mu.Lock()
+	wd, ok := s.withdrawals[withdrawalID]
+	if !ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("withdrawal %s not found", withdrawalID)
+	}
+	if wd.State.IsTerminal() {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("withdrawal %s is already %s", withdrawalID, wd.State)
+	}
+	if err := wd.State.ValidateTransition(state); err != nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("withdrawal %s : %w", withdrawalID, err)
+	}
+	s.onHold -= wd.Amount + wd.Details.FeeLimit
+	if state.IsSuccess() {
+		wd.FeePaid = s.feeEstimator(wd.Amount)
+		if wd.FeePaid > wd.Details.FeeLimit {
+			wd.FeePaid = wd.Details.FeeLimit
+		}
+		s.balance -= wd.Amount + wd.FeePaid
+	}
+	wd.State = state
+	s.mu.Unlock()
+
+	err := s.emitIfSubscribed(ctx, rls.WebhookEvent{ID: wd.ID, Type: rls.WebhookTypeWithdrawal, State: string(state)})
+	return copyWithdrawal(wd), err
+}
+
+// EmitWebhook sends event to the subscribed webhook URL, signed with the River-Signature header
+func (s *Server) EmitWebhook(ctx context.Context, event rls.WebhookEvent) error {
+	s.mu.Lock()
+	webhook := s.webhook
+	s.mu.Unlock()
+	if webhook == nil {
+		return fmt.Errorf("no webhook subscribed")
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	header, err := SignWebhook(s.webhookSecret, s.clock(), body)
+	if err != nil {
+		return err
+	}
+	headerValue, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(rls.WebhookHeaderKey, string(headerValue))
+	res, err := s.webhookClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to emit webhook : %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("failed to emit webhook : receiver returned %d", res.StatusCode)
+	}
+	return nil
+}
+
+// SignWebhook returns the River-Signature header for body sent at t, as verified by rls.VerifyWebhookSignature
+func SignWebhook(secret string, t time.Time, body []byte) (*rls.WebhookHeader, error) {
+	key, err := hex.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode webhook secret : %w", err)
+	}
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(fmt.Sprintf("%s.%s", timestamp, body)))
+	return &rls.WebhookHeader{
+		Timestamp: timestamp,
+		Signature: hex.EncodeToString(mac.Sum(nil)),
+	}, nil
+}
+
+func (s *Server) emitIfSubscribed(ctx context.Context, event rls.WebhookEvent) error {
+	s.mu.Lock()
+	subscribed := s.webhook != nil && s.webhook.Enabled
+	s.mu.Unlock()
+	if !subscribed {
+		return nil
+	}
+	return s.EmitWebhook(ctx, event)
+}
+
+// HTTP handlers
+
+// apiError is the error body returned by the simulator
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "invalid credential")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.URL.Path == "/":
+		writeJSON(w, http.StatusOK, struct{}{})
+	case parts[0] == "lightning" && len(parts) == 2 && r.Method == http.MethodPut:
+		switch parts[1] {
+		case "parse_invoice":
+			s.parseInvoice(w, r)
+		case "estimate_fee":
+			s.estimateFee(w, r)
+		default:
+			writeError(w, http.StatusNotFound, "not_found", "unknown endpoint")
+		}
+	case parts[0] == "accounts" && len(parts) >= 2:
+		if parts[1] != s.accountID {
+			writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("account %s not found", parts[1]))
+			return
+		}
+		s.serveAccount(w, r, parts[2:])
+	default:
+		writeError(w, http.StatusNotFound, "not_found", "unknown endpoint")
+	}
+}
+
+func (s *Server) serveAccount(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", r.Method)
+			return
+		}
+		s.getAccount(w)
+		return
+	}
+
+	resource, id := parts[0], ""
+	if len(parts) == 2 {
+		id = parts[1]
+	} else if len(parts) > 2 {
+		writeError(w, http.StatusNotFound, "not_found", "unknown endpoint")
+		return
+	}
+
+	switch {
+	case resource == "deposit_intents" && id == "" && r.Method == http.MethodPost:
+		s.newInvoice(w, r)
+	case resource == "deposit_intents" && id == "" && r.Method == http.MethodGet:
+		s.listInvoices(w, r)
+	case resource == "deposit_intents" && r.Method == http.MethodGet:
+		s.getInvoice(w, id)
+	case resource == "deposits" && id == "" && r.Method == http.MethodGet:
+		s.listDeposits(w, r)
+	case resource == "deposits" && r.Method == http.MethodGet:
+		s.getDeposit(w, id)
+	case resource == "withdrawals" && id == "" && r.Method == http.MethodPost:
+		s.newWithdrawal(w, r)
+	case resource == "withdrawals" && id == "" && r.Method == http.MethodGet:
+		s.listWithdrawals(w, r)
+	case resource == "withdrawals" && r.Method == http.MethodGet:
+		s.getWithdrawal(w, id)
+	case resource == "webhooks" && id == "":
+		s.serveWebhooks(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "not_found", "unknown endpoint")
+	}
+}
+
+func (s *Server) getAccount(w http.ResponseWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, &rls.Account{
+		ID:               s.accountID,
+		Balance:          s.balance,
+		AvailableBalance: s.balance - s.onHold,
+		CurrencyBalances: []*rls.CurrencyBalance{{
+			Currency:     rls.CurrencyBTC,
+			Amount:       s.balance.Sats(),
+			AmountOnHold: s.onHold.Sats(),
+		}},
+	})
+}
+
+func (s *Server) newInvoice(w http.ResponseWriter, r *http.Request) {
+	var invReq rls.InvoiceRequest
+	if !readJSON(w, r, &invReq) {
+		return
+	}
+	if invReq.Amount < 0 || invReq.Amount%rls.Satoshi != 0 {
+		writeError(w, http.StatusBadRequest, "invalid_amount", "amount must be a non-negative number of whole sats")
+		return
+	}
+	if !supportedCurrency(invReq.Currency) {
+		writeError(w, http.StatusBadRequest, "unsupported_currency", fmt.Sprintf("unsupported currency %s", invReq.Currency))
+		return
+	}
+	if invReq.Network == "" {
+		invReq.Network = rls.NetworkLN
+	}
+	if invReq.Network != rls.NetworkLN {
+		writeError(w, http.StatusBadRequest, "invalid_network", fmt.Sprintf("unsupported network %s", invReq.Network))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := idempotencyKey(r)
+	if prev, ok := s.idempotent[key].(*rls.Invoice); ok && key != "" {
+		writeJSON(w, http.StatusOK, prev)
+		return
+	}
+	invoice := &rls.Invoice{
+		ID:        s.newID("di"),
+		Invoice:   s.newInvoiceString(invReq.Amount),
+		Network:   invReq.Network,
+		Timestamp: s.now(),
+	}
+	s.invoices[invoice.ID] = invoice
+	s.payable[invoice.Invoice] = &rls.DecodedInvoice{Amount: invReq.Amount, Memo: invReq.Label, NodeID: "02" + hex.EncodeToString(randomBytes(32)), Invoice: invoice.Invoice}
+	if key != "" {
+		s.idempotent[key] = invoice
+	}
+	writeJSON(w, http.StatusOK, invoice)
+}
+
+func (s *Server) getInvoice(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	invoice, ok := s.invoices[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("deposit intent %s not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, invoice)
+}
+
+func (s *Server) listInvoices(w http.ResponseWriter, r *http.Request) {
+	limit, cursor, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	timestamps := make([]int64, 0, len(s.invoices))
+	byTimestamp := make(map[int64]*rls.Invoice, len(s.invoices))
+	for _, invoice := range s.invoices {
+		timestamps = append(timestamps, invoice.Timestamp)
+		byTimestamp[invoice.Timestamp] = invoice
+	}
+	page, next := paginate(timestamps, limit, cursor)
+	list := rls.InvoiceList{Invoices: []rls.Invoice{}, NextTimestamp: next}
+	for _, ts := range page {
+		list.Invoices = append(list.Invoices, *byTimestamp[ts])
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) getDeposit(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deposit, ok := s.deposits[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("deposit %s not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, deposit)
+}
+
+func (s *Server) listDeposits(w http.ResponseWriter, r *http.Request) {
+	limit, cursor, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	timestamps := make([]int64, 0, len(s.deposits))
+	byTimestamp := make(map[int64]*rls.Deposit, len(s.deposits))
+	for _, deposit := range s.deposits {
+		timestamps = append(timestamps, deposit.Timestamp)
+		byTimestamp[deposit.Timestamp] = deposit
+	}
+	page, next := paginate(timestamps, limit, cursor)
+	list := rls.DepositList{Deposits: []rls.Deposit{}, NextTimestamp: next}
+	for _, ts := range page {
+		list.Deposits = append(list.Deposits, *byTimestamp[ts])
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) newWithdrawal(w http.ResponseWriter, r *http.Request) {
+	var req rls.Withdrawal
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Amount <= 0 || req.Amount%rls.Satoshi != 0 {
+		writeError(w, http.StatusBadRequest, "invalid_amount", "amount must be a positive number of whole sats")
+		return
+	}
+	if req.Details.FeeLimit < 0 || req.Details.FeeLimit%rls.Satoshi != 0 {
+		writeError(w, http.StatusBadRequest, "invalid_fee_limit", "fee_limit must be a non-negative number of whole sats")
+		return
+	}
+	if !supportedCurrency(req.Currency) {
+		writeError(w, http.StatusBadRequest, "unsupported_currency", fmt.Sprintf("unsupported currency %s", req.Currency))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := idempotencyKey(r)
+	if prev, ok := s.idempotent[key].(*rls.Withdrawal); ok && key != "" {
+		writeJSON(w, http.StatusOK, prev)
+		return
+	}
+	if _, ok := s.decode(req.Details.Invoice); !ok {
+		writeError(w, http.StatusBadRequest, "invalid_invoice", "invalid invoice")
+		return
+	}
+	if req.Amount+req.Details.FeeLimit > s.balance-s.onHold {
+		writeError(w, http.StatusBadRequest, "insufficient_funds", "insufficient funds to cover amount and fee limit")
+		return
+	}
+
+	wd := &rls.Withdrawal{
+		Amount:    req.Amount,
+		Currency:  rls.CurrencyBTC,
+		Details:   req.Details,
+		State:     rls.WithdrawalStatePending,
+		ID:        s.newID("wd"),
+		Timestamp: s.now(),
+	}
+	if wd.Details.Network == "" {
+		wd.Details.Network = rls.NetworkLN
+	}
+	s.withdrawals[wd.ID] = wd
+	s.onHold += wd.Amount + wd.Details.FeeLimit
+	if key != "" {
+		s.idempotent[key] = wd
+	}
+	writeJSON(w, http.StatusOK, wd)
+}
+
+func (s *Server) getWithdrawal(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wd, ok := s.withdrawals[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("withdrawal %s not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, wd)
+}
+
+func (s *Server) listWithdrawals(w http.ResponseWriter, r *http.Request) {
+	limit, cursor, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	timestamps := make([]int64, 0, len(s.withdrawals))
+	byTimestamp := make(map[int64]*rls.Withdrawal, len(s.withdrawals))
+	for _, wd := range s.withdrawals {
+		timestamps = append(timestamps, wd.Timestamp)
+		byTimestamp[wd.Timestamp] = wd
+	}
+	page, next := paginate(timestamps, limit, cursor)
+	list := rls.WithdrawalList{Withdrawals: []rls.Withdrawal{}, NextTimestamp: next}
+	for _, ts := range page {
+		list.Withdrawals = append(list.Withdrawals, *byTimestamp[ts])
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) serveWebhooks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.webhook == nil {
+			writeError(w, http.StatusNotFound, "not_found", "no webhook subscribed")
+			return
+		}
+		writeJSON(w, http.StatusOK, &rls.Webhook{URL: s.webhook.URL, Enabled: s.webhook.Enabled})
+	case http.MethodPost:
+		var body struct {
+			URL string `json:"url"`
+		}
+		if !readJSON(w, r, &body) {
+			return
+		}
+		if !strings.HasPrefix(body.URL, "http://") && !strings.HasPrefix(body.URL, "https://") {
+			writeError(w, http.StatusBadRequest, "invalid_url", "url must be http or https")
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.webhook != nil {
+			writeError(w, http.StatusConflict, "webhook_exists", "a webhook is already subscribed")
+			return
+		}
+		s.webhook = &rls.Webhook{URL: body.URL, Enabled: true}
+		writeJSON(w, http.StatusOK, &rls.Webhook{URL: body.URL, Secret: s.webhookSecret, Enabled: true})
+	case http.MethodDelete:
+		var body struct {
+			URL string `json:"url"`
+		}
+		if !readJSON(w, r, &body) {
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.webhook == nil || s.webhook.URL != body.URL {
+			writeError(w, http.StatusNotFound, "not_found", "webhook not found")
+			return
+		}
+		s.webhook = nil
+		writeJSON(w, http.StatusOK, struct{}{})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", r.Method)
+	}
+}
+
+func (s *Server) parseInvoice(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Destination string `json:"destination"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	decoded, ok := s.decode(body.Destination)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_invoice", "invalid invoice")
+		return
+	}
+	writeJSON(w, http.StatusOK, decoded)
+}
+
+func (s *Server) estimateFee(w http.ResponseWriter, r *http.Request) {
+	var req rls.FeeEstimateRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Amount < 0 || req.Amount%rls.Satoshi != 0 {
+		writeError(w, http.StatusBadRequest, "invalid_amount", "amount must be a non-negative number of whole sats")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	decoded, ok := s.decode(req.Destination)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_invoice", "invalid invoice")
+		return
+	}
+	amount := req.Amount
+	if amount == 0 {
+		amount = decoded.Amount
+	}
+	writeJSON(w, http.StatusOK, &rls.FeeEstimate{
+		Amount:  amount,
+		Invoice: req.Destination,
+		Fee:     s.feeEstimator(amount),
+	})
+}
+
+// helpers
+
+// authorized checks the basic auth credential built by the rls client
+func (s *Server) authorized(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	const prefix = "basic "
+	if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return false
+	}
+	decoded, err := b64.StdEncoding.DecodeString(auth[len(prefix):])
+	if err != nil {
+		return false
+	}
+	username := strings.SplitN(string(decoded), ":", 2)[0]
+	return hmac.Equal([]byte(username), []byte(s.apiKey))
+}
+
+// decode returns the decoded form of invoice. s.mu must be held.
+func (s *Server) decode(invoice string) (*rls.DecodedInvoice, bool) {
+	if decoded, ok := s.payable[invoice]; ok {
+		return decoded, true
+	}
+	amount, ok := parseInvoiceAmount(invoice)
+	if !ok {
+		return nil, false
+	}
+	return &rls.DecodedInvoice{Amount: amount, Invoice: invoice}, true
+}
+
+// now returns a unique, strictly increasing UNIX timestamp so that pagination by timestamp is stable. s.mu must be held.
+func (s *Server) now() int64 {
+	ts := s.clock().Unix()
+	if ts <= s.lastTimestamp {
+		ts = s.lastTimestamp + 1
+	}
+	s.lastTimestamp = ts
+	return ts
+}
+
+// newID returns a new object ID with the given prefix. s.mu must be held.
+func (s *Server) newID(prefix string) string {
+	s.nextID++
+	return fmt.Sprintf("%s_%06d", prefix, s.nextID)
+}
+
+// supportedCurrency returns true for the only currency the simulator holds, BTC, or an empty currency
+func supportedCurrency(currency string) bool {
+	return currency == "" || strings.EqualFold(currency, rls.CurrencyBTC)
+}
+
+// newInvoiceString returns a regtest BOLT-11-looking invoice encoding amount in its human readable part
+func (s *Server) newInvoiceString(amount rls.Amount) string {
+	hrp := "lnbcrt"
+	if amount > 0 {
+		// 1 sat = 10n, 1 msat = 10p
+		hrp += fmt.Sprintf("%dp", amount.MSats()*10)
+	}
+	return hrp + "1" + encodeBech32Chars(randomBytes(48))
+}
+
+// parseInvoiceAmount parses the amount from the human readable part of a BOLT-11 invoice
+func parseInvoiceAmount(invoice string) (rls.Amount, bool) {
+	invoice = strings.ToLower(invoice)
+	sep := strings.LastIndex(invoice, "1")
+	if !strings.HasPrefix(invoice, "ln") || sep < 4 || len(invoice)-sep < 8 {
+		return 0, false
+	}
+	hrp := invoice[2:sep]
+	for _, prefix := range []string{"bcrt", "bc", "tbs", "tb", "sb"} {
+		if strings.HasPrefix(hrp, prefix) {
+			hrp = strings.TrimPrefix(hrp, prefix)
+			break
+		}
+	}
+	if hrp == "" {
+		return 0, true
+	}
+	// amounts are expressed in BTC with a multiplier, converted here to tenths of a msat (1 msat = 10p)
+	multipliers := map[byte]int64{'m': 1000000000, 'u': 1000000, 'n': 1000, 'p': 1}
+	unit := hrp[len(hrp)-1]
+	if multiplier, ok := multipliers[unit]; ok {
+		value, err := strconv.ParseInt(hrp[:len(hrp)-1], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return rls.MSats(value * multiplier / 10), true
+	}
+	value, err := strconv.ParseInt(hrp, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return rls.Amount(value) * rls.Bitcoin, true
+}
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+func encodeBech32Chars(b []byte) string {
+	out := make([]byte, len(b))
+	for i, c := range b {
+		out[i] = bech32Charset[int(c)%len(bech32Charset)]
+	}
+	return string(out)
+}
+
+// paginate returns the page of timestamps, newest first, starting at cursor (inclusive, 0 for the
+// newest) and the timestamp of the first item of the next page, or 0 if there is none
+func paginate(timestamps []int64, limit int, cursor int64) ([]int64, int64) {
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] > timestamps[j] })
+	start := 0
+	if cursor != 0 {
+		start = sort.Search(len(timestamps), func(i int) bool { return timestamps[i] <= cursor })
+	}
+	end := start + limit
+	if end >= len(timestamps) {
+		return timestamps[start:], 0
+	}
+	return timestamps[start:end], timestamps[end]
+}
+
+// pageParams parses the limit and next_timestamp query parameters
+func pageParams(w http.ResponseWriter, r *http.Request) (int, int64, bool) {
+	limit := DefaultPageLimit
+	query := r.URL.Query()
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxPageLimit {
+			writeError(w, http.StatusBadRequest, "invalid_limit", fmt.Sprintf("limit must be between 1 and %d", MaxPageLimit))
+			return 0, 0, false
+		}
+		limit = n
+	}
+	var cursor int64
+	if v := query.Get("next_timestamp"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid_next_timestamp", "next_timestamp must be a UNIX timestamp")
+			return 0, 0, false
+		}
+		cursor = n
+	}
+	return limit, cursor, true
+}
+
+func idempotencyKey(r *http.Request) string {
+	return r.Header.Get(rls.IdempotencyKeyHeader)
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, apiError{Code: code, Message: message})
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func copyDeposit(d *rls.Deposit) *rls.Deposit {
+	cp := *d
+	return &cp
+}
+
+func copyWithdrawal(wd *rls.Withdrawal) *rls.Withdrawal {
+	cp := *wd
+	return &cp
+}
@@ -0,0 +1,224 @@
+package rlstest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SachinMeier/rls-client"
+)
+
+func TestInvoiceAndDeposit(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	client := srv.Client()
+	ctx := context.Background()
+
+	invoice, err := client.NewInvoiceContext(ctx, rls.Sats(1000), "coffee", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := client.DecodeInvoiceContext(ctx, invoice.Invoice)
+	if err != nil || decoded.Amount != rls.Sats(1000) || decoded.Memo != "coffee" {
+		t.Fatalf("got %+v, %v", decoded, err)
+	}
+
+	deposit, err := srv.SettleInvoice(ctx, invoice.ID, rls.Sats(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := client.GetDepositContext(ctx, deposit.ID)
+	if err != nil || got.Amount != rls.Sats(1000) || got.State != rls.DepositStateSuccess {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+	account, err := client.GetAccountContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance != rls.Sats(1000) || account.AvailableBalance != rls.Sats(1000) {
+		t.Errorf("expected a balance of 1000 sats, got %+v", account)
+	}
+}
+
+func TestWithdrawalLifecycle(t *testing.T) {
+	srv := NewServerWithOptions(Options{Balance: rls.Sats(10000)})
+	defer srv.Close()
+	client := srv.Client()
+	ctx := context.Background()
+
+	invoice := srv.NewPayableInvoice(rls.Sats(5000), "")
+	wd, err := client.NewWithdrawalContext(ctx, rls.NewWithdrawalWithFeeLimit(5000, invoice, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wd.State != rls.WithdrawalStatePending {
+		t.Fatalf("expected a pending withdrawal, got %s", wd.State)
+	}
+	if _, err := client.NewWithdrawalContext(ctx, rls.NewWithdrawalWithFeeLimit(5000, invoice, 100)); !errors.Is(err, rls.ErrInsufficientFunds) {
+		t.Fatalf("expected insufficient funds while the first withdrawal is on hold, got %v", err)
+	}
+
+	done, err := srv.SucceedWithdrawal(ctx, wd.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.State != rls.WithdrawalStateSuccess || done.FeePaid != rls.Sats(5) {
+		t.Errorf("got %+v", done)
+	}
+	if _, err := srv.FailWithdrawal(ctx, wd.ID); err == nil {
+		t.Error("expected an error failing a finished withdrawal")
+	}
+	account, err := client.GetAccountContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance != rls.Sats(4995) || account.GetReservedBalance() != 0 {
+		t.Errorf("expected a balance of 4995 sats, got %+v", account)
+	}
+}
+
+func TestFailWithdrawalReleasesFunds(t *testing.T) {
+	srv := NewServerWithOptions(Options{Balance: rls.Sats(1000)})
+	defer srv.Close()
+	client := srv.Client()
+	ctx := context.Background()
+
+	wd, err := client.NewWithdrawalContext(ctx, rls.NewWithdrawalWithFeeLimit(900, srv.NewPayableInvoice(rls.Sats(900), ""), 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srv.FailWithdrawal(ctx, wd.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.NewWithdrawalContext(ctx, rls.NewWithdrawalWithFeeLimit(900, srv.NewPayableInvoice(rls.Sats(900), ""), 100)); err != nil {
+		t.Fatalf("funds not released: %v", err)
+	}
+}
+
+func TestWithdrawalIdempotency(t *testing.T) {
+	srv := NewServerWithOptions(Options{Balance: rls.Sats(10000)})
+	defer srv.Close()
+	client := srv.Client()
+	ctx := context.Background()
+
+	withdrawal := rls.NewWithdrawalWithFeeLimit(1000, srv.NewPayableInvoice(rls.Sats(1000), ""), 10)
+	first, err := client.NewWithdrawalContext(ctx, withdrawal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := client.NewWithdrawalContext(ctx, withdrawal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID != second.ID {
+		t.Errorf("replay created a second withdrawal: %s, %s", first.ID, second.ID)
+	}
+	list, err := client.ListWithdrawalsContext(ctx, 10, 0)
+	if err != nil || len(list.Withdrawals) != 1 {
+		t.Fatalf("got %+v, %v", list, err)
+	}
+}
+
+func TestPagination(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	srv := NewServerWithOptions(Options{Clock: func() time.Time { return now }})
+	defer srv.Close()
+	client := srv.Client()
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if _, err := client.NewInvoiceContext(ctx, rls.Sats(1), "", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var seen []int64
+	var cursor int64
+	for {
+		page, err := client.GetInvoicesContext(ctx, 2, cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, invoice := range page.Invoices {
+			seen = append(seen, invoice.Timestamp)
+		}
+		if page.NextTimestamp == 0 {
+			break
+		}
+		cursor = page.NextTimestamp
+	}
+	if len(seen) != 5 {
+		t.Fatalf("expected 5 invoices, got %v", seen)
+	}
+	for i := 1; i < len(seen); i++ {
+		if seen[i] >= seen[i-1] {
+			t.Fatalf("invoices not newest first: %v", seen)
+		}
+	}
+	if _, err := client.GetInvoicesContext(ctx, MaxPageLimit+1, 0); err == nil {
+		t.Error("expected an error for a limit above MaxPageLimit")
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	cfg := rls.NewConfig(srv.URL, "wrong", srv.AccountID(), "", nil)
+	client, err := rls.New(*cfg, rls.WithHTTPClient(srv.Server.Client()), rls.WithRetryPolicy(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetAccountContext(context.Background()); !errors.Is(err, rls.ErrUnauthorized) {
+		t.Fatalf("expected unauthorized, got %v", err)
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	client := srv.Client()
+	ctx := context.Background()
+
+	events := make(chan rls.WebhookEvent, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var header rls.WebhookHeader
+		if err := json.Unmarshal([]byte(r.Header.Get(rls.WebhookHeaderKey)), &header); err != nil {
+			t.Errorf("failed to parse signature header : %v", err)
+		}
+		if err := client.VerifyWebhookSignature(string(body), &header); err != nil {
+			t.Error(err)
+		}
+		var event rls.WebhookEvent
+		json.Unmarshal(body, &event)
+		events <- event
+	}))
+	defer receiver.Close()
+
+	if _, err := client.SubscribeToWebhookContext(ctx, receiver.URL); err != nil {
+		t.Fatal(err)
+	}
+	invoice, err := client.NewInvoiceContext(ctx, rls.Sats(10), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deposit, err := srv.SettleInvoice(ctx, invoice.ID, rls.Sats(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := <-events
+	if state, ok := event.DepositState(); !ok || event.ID != deposit.ID || state != rls.DepositStateSuccess {
+		t.Errorf("got %+v", event)
+	}
+
+	if err := client.DeleteWebhookContext(ctx, receiver.URL); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.EmitWebhook(ctx, rls.WebhookEvent{ID: "x"}); err == nil {
+		t.Error("expected an error emitting without a subscription")
+	}
+}
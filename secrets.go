@@ -0,0 +1,90 @@
+package rls
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// SecretSource provides a secret such as the API key or the webhook secret, so it does not
+// have to be passed in the environment
+type SecretSource interface {
+	Secret(ctx context.Context) (string, error)
+}
+
+// SecretSourceFunc adapts a function to a SecretSource
+type SecretSourceFunc func(ctx context.Context) (string, error)
+
+// Secret calls f
+func (f SecretSourceFunc) Secret(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// StaticSecret returns a SecretSource of a secret already in memory
+func StaticSecret(secret string) SecretSource {
+	return SecretSourceFunc(func(context.Context) (string, error) {
+		return secret, nil
+	})
+}
+
+// FileSecret returns a SecretSource reading the secret from the file at path, without the trailing newline
+func FileSecret(path string) SecretSource {
+	return SecretSourceFunc(func(context.Context) (string, error) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file : %w", err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	})
+}
+
+// CommandSecret returns a SecretSource running command with sh -c and reading the secret from its
+// first line of stdout, like a git credential helper, e.g. "pass show river/api-key".
+// The command's stderr is passed through so it can prompt the user.
+func CommandSecret(command string) SecretSource {
+	return SecretSourceFunc(func(ctx context.Context) (string, error) {
+		var stdout bytes.Buffer
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Stdout = &stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("failed to run secret command : %w", err)
+		}
+		secret := strings.SplitN(stdout.String(), "\n", 2)[0]
+		if secret = strings.TrimRight(secret, "\r"); secret == "" {
+			return "", fmt.Errorf("secret command printed no secret")
+		}
+		return secret, nil
+	})
+}
+
+// KeystoreSecret returns a SecretSource decrypting the entry name of keystore with the passphrase
+// returned by passphrase
+func KeystoreSecret(keystore *Keystore, name string, passphrase func() ([]byte, error)) SecretSource {
+	return SecretSourceFunc(func(context.Context) (string, error) {
+		pass, err := passphrase()
+		if err != nil {
+			return "", fmt.Errorf("failed to read keystore passphrase : %w", err)
+		}
+		return keystore.Get(name, pass)
+	})
+}
+
+// NewConfigFromSources creates a new Config reading the API key and the webhook secret from sources.
+// webhookSecret may be nil.
+func NewConfigFromSources(ctx context.Context, baseURL string, apiKey SecretSource, accountID string, webhookSecret SecretSource, extraHeaders map[string]string) (*Config, error) {
+	key, err := apiKey.Secret(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load api key : %w", err)
+	}
+	var secret string
+	if webhookSecret != nil {
+		if secret, err = webhookSecret.Secret(ctx); err != nil {
+			return nil, fmt.Errorf("failed to load webhook secret : %w", err)
+		}
+	}
+	return NewConfig(baseURL, key, accountID, secret, extraHeaders), nil
+}
@@ -0,0 +1,33 @@
+package rls
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSecretSources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte("from_file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		source  SecretSource
+		want    string
+		wantErr bool
+	}{
+		{StaticSecret("static"), "static", false},
+		{FileSecret(path), "from_file", false},
+		{FileSecret(path + ".missing"), "", true},
+		{CommandSecret("printf 'first\\nsecond\\n'"), "first", false},
+		{CommandSecret("true"), "", true},
+		{CommandSecret("exit 1"), "", true},
+	}
+	for i, tt := range tests {
+		got, err := tt.source.Secret(context.Background())
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%d: got %q, %v", i, got, err)
+		}
+	}
+}
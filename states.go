@@ -0,0 +1,119 @@
+package rls
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidTransition is returned by ValidateTransition for transitions the lifecycle does not allow
+var ErrInvalidTransition = errors.New("rls: invalid state transition")
+
+// WithdrawalState is the lifecycle state of a withdrawal.
+// States unknown to this client decode without error and are neither terminal nor successful.
+type WithdrawalState string
+
+// Known withdrawal states
+const (
+	WithdrawalStatePending WithdrawalState = "PENDING"
+	WithdrawalStateSuccess WithdrawalState = "SUCCESS"
+	WithdrawalStateFail    WithdrawalState = "FAIL"
+)
+
+// withdrawalTransitions lists the states each withdrawal state can move to
+var withdrawalTransitions = map[WithdrawalState][]WithdrawalState{
+	WithdrawalStatePending: {WithdrawalStateSuccess, WithdrawalStateFail},
+	WithdrawalStateSuccess: nil,
+	WithdrawalStateFail:    nil,
+}
+
+// IsKnown returns true if s is one of the known withdrawal states
+func (s WithdrawalState) IsKnown() bool {
+	_, ok := withdrawalTransitions[s]
+	return ok
+}
+
+// IsTerminal returns true if the withdrawal can no longer change state
+func (s WithdrawalState) IsTerminal() bool {
+	return s == WithdrawalStateSuccess || s == WithdrawalStateFail
+}
+
+// IsSuccess returns true if the withdrawal was paid
+func (s WithdrawalState) IsSuccess() bool {
+	return s == WithdrawalStateSuccess
+}
+
+// CanTransitionTo returns true if a withdrawal in state s can move to next. Staying in the same state is allowed.
+func (s WithdrawalState) CanTransitionTo(next WithdrawalState) bool {
+	if s == next {
+		return true
+	}
+	for _, allowed := range withdrawalTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTransition returns an error wrapping ErrInvalidTransition if s cannot move to next
+func (s WithdrawalState) ValidateTransition(next WithdrawalState) error {
+	if !s.CanTransitionTo(next) {
+		return fmt.Errorf("%w : withdrawal %s -> %s", ErrInvalidTransition, s, next)
+	}
+	return nil
+}
+
+// DepositState is the lifecycle state of a deposit.
+// States unknown to this client decode without error and are neither terminal nor successful.
+type DepositState string
+
+// Known deposit states
+const (
+	DepositStatePending DepositState = "PENDING"
+	DepositStateSuccess DepositState = "SUCCESS"
+	DepositStateFail    DepositState = "FAIL"
+)
+
+// depositTransitions lists the states each deposit state can move to
+var depositTransitions = map[DepositState][]DepositState{
+	DepositStatePending: {DepositStateSuccess, DepositStateFail},
+	DepositStateSuccess: nil,
+	DepositStateFail:    nil,
+}
+
+// IsKnown returns true if s is one of the known deposit states
+func (s DepositState) IsKnown() bool {
+	_, ok := depositTransitions[s]
+	return ok
+}
+
+// IsTerminal returns true if the deposit can no longer change state
+func (s DepositState) IsTerminal() bool {
+	return s == DepositStateSuccess || s == DepositStateFail
+}
+
+// IsSuccess returns true if the deposit was credited
+func (s DepositState) IsSuccess() bool {
+	return s == DepositStateSuccess
+}
+
+// CanTransitionTo returns true if a deposit in state s can move to next. Staying in the same state is allowed.
+func (s DepositState) CanTransitionTo(next DepositState) bool {
+	if s == next {
+		return true
+	}
+	for _, allowed := range depositTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTransition returns an error wrapping ErrInvalidTransition if s cannot move to next
+func (s DepositState) ValidateTransition(next DepositState) error {
+	if !s.CanTransitionTo(next) {
+		return fmt.Errorf("%w : deposit %s -> %s", ErrInvalidTransition, s, next)
+	}
+	return nil
+}
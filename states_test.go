@@ -0,0 +1,53 @@
+package rls
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestWithdrawalStateTransitions(t *testing.T) {
+	tests := []struct {
+		from, to WithdrawalState
+		want     bool
+	}{
+		{WithdrawalStatePending, WithdrawalStatePending, true},
+		{WithdrawalStatePending, WithdrawalStateSuccess, true},
+		{WithdrawalStatePending, WithdrawalStateFail, true},
+		{WithdrawalStateSuccess, WithdrawalStateFail, false},
+		{WithdrawalStateFail, WithdrawalStatePending, false},
+		{WithdrawalState("REFUNDED"), WithdrawalStateSuccess, false},
+	}
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+			t.Errorf("%s -> %s = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+		if err := tt.from.ValidateTransition(tt.to); (err == nil) != tt.want || (err != nil && !errors.Is(err, ErrInvalidTransition)) {
+			t.Errorf("ValidateTransition(%s -> %s) = %v", tt.from, tt.to, err)
+		}
+	}
+}
+
+func TestDepositStateTransitions(t *testing.T) {
+	if !DepositStatePending.CanTransitionTo(DepositStateSuccess) || DepositStateSuccess.CanTransitionTo(DepositStatePending) {
+		t.Error("unexpected deposit transitions")
+	}
+	if err := DepositStateFail.ValidateTransition(DepositStateSuccess); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestUnknownStatesDecode(t *testing.T) {
+	var wd Withdrawal
+	if err := json.Unmarshal([]byte(`{"state":"REFUNDED"}`), &wd); err != nil {
+		t.Fatal(err)
+	}
+	if wd.State.IsKnown() || wd.State.IsTerminal() || wd.State.IsSuccess() {
+		t.Errorf("unknown state %s treated as known", wd.State)
+	}
+	for _, s := range []DepositState{DepositStatePending, DepositStateSuccess, DepositStateFail} {
+		if !s.IsKnown() || s.IsTerminal() != (s != DepositStatePending) || s.IsSuccess() != (s == DepositStateSuccess) {
+			t.Errorf("unexpected predicates for %s", s)
+		}
+	}
+}
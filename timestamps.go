@@ -0,0 +1,172 @@
+package rls
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// TimestampUnit is the unit of RLS timestamps
+type TimestampUnit int32
+
+// Timestamp units
+const (
+	// TimestampAuto detects the unit from the timestamps returned by RLS
+	TimestampAuto TimestampUnit = iota
+	TimestampSeconds
+	TimestampMilliseconds
+)
+
+// millisecondThreshold is the smallest timestamp treated as milliseconds. As seconds it would be
+// in the year 33658; as milliseconds it is September 2001.
+const millisecondThreshold = 1_000_000_000_000
+
+// TimeFromTimestamp converts an RLS timestamp to a time.Time, detecting whether it is in seconds
+// or milliseconds. 0 returns the zero time.
+func TimeFromTimestamp(ts int64) time.Time {
+	switch {
+	case ts == 0:
+		return time.Time{}
+	case ts >= millisecondThreshold || ts <= -millisecondThreshold:
+		return time.UnixMilli(ts)
+	default:
+		return time.Unix(ts, 0)
+	}
+}
+
+// timestampUnitOf returns the unit of the RLS timestamp ts, or TimestampAuto for 0
+func timestampUnitOf(ts int64) TimestampUnit {
+	switch {
+	case ts == 0:
+		return TimestampAuto
+	case ts >= millisecondThreshold || ts <= -millisecondThreshold:
+		return TimestampMilliseconds
+	default:
+		return TimestampSeconds
+	}
+}
+
+// TimestampFromTime converts t to an RLS timestamp in seconds. The zero time returns 0.
+// Use TimestampFromTimeIn when the API returns milliseconds.
+func TimestampFromTime(t time.Time) int64 {
+	return TimestampFromTimeIn(t, TimestampSeconds)
+}
+
+// TimestampFromTimeIn converts t to an RLS timestamp in unit, seconds for TimestampAuto. The zero time returns 0.
+func TimestampFromTimeIn(t time.Time, unit TimestampUnit) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	if unit == TimestampMilliseconds {
+		return t.UnixMilli()
+	}
+	return t.Unix()
+}
+
+// observeTimestamps records the unit of timestamps returned by a list call
+func (rls *RLSClient) observeTimestamps(timestamps ...int64) {
+	for _, ts := range timestamps {
+		if unit := timestampUnitOf(ts); unit != TimestampAuto {
+			atomic.StoreInt32(&rls.observedUnit, int32(unit))
+			return
+		}
+	}
+}
+
+// cursorFromTime converts t to a cursor in the unit RLS uses. If the unit is neither configured nor
+// observed yet, probe is called to list the most recent item and observe its timestamp.
+// The zero time returns 0.
+func (rls *RLSClient) cursorFromTime(ctx context.Context, t time.Time, probe func(ctx context.Context) error) (int64, error) {
+	if t.IsZero() {
+		return 0, nil
+	}
+	unit := rls.TimestampUnit
+	if unit == TimestampAuto {
+		unit = TimestampUnit(atomic.LoadInt32(&rls.observedUnit))
+	}
+	if unit == TimestampAuto {
+		if err := probe(ctx); err != nil {
+			return 0, err
+		}
+		unit = TimestampUnit(atomic.LoadInt32(&rls.observedUnit))
+	}
+	return TimestampFromTimeIn(t, unit), nil
+}
+
+// probeDeposits lists the most recent deposit so its timestamp unit is observed
+func (rls *RLSClient) probeDeposits(ctx context.Context) error {
+	_, err := rls.GetDepositsContext(ctx, 1, 0)
+	return err
+}
+
+// probeWithdrawals lists the most recent withdrawal so its timestamp unit is observed
+func (rls *RLSClient) probeWithdrawals(ctx context.Context) error {
+	_, err := rls.ListWithdrawalsContext(ctx, 1, 0)
+	return err
+}
+
+// probeInvoices lists the most recent invoice so its timestamp unit is observed
+func (rls *RLSClient) probeInvoices(ctx context.Context) error {
+	_, err := rls.GetInvoicesContext(ctx, 1, 0)
+	return err
+}
+
+// Time returns the time the invoice was created
+func (inv Invoice) Time() time.Time {
+	return TimeFromTimestamp(inv.Timestamp)
+}
+
+// Time returns the time the deposit was made
+func (dep Deposit) Time() time.Time {
+	return TimeFromTimestamp(dep.Timestamp)
+}
+
+// Time returns the time the withdrawal was created
+func (wd Withdrawal) Time() time.Time {
+	return TimeFromTimestamp(wd.Timestamp)
+}
+
+// NextTime returns the cursor of the next page. The zero time means there are no more pages.
+func (list InvoiceList) NextTime() time.Time {
+	return TimeFromTimestamp(list.NextTimestamp)
+}
+
+// NextTime returns the cursor of the next page. The zero time means there are no more pages.
+func (list DepositList) NextTime() time.Time {
+	return TimeFromTimestamp(list.NextTimestamp)
+}
+
+// NextTime returns the cursor of the next page. The zero time means there are no more pages.
+func (list WithdrawalList) NextTime() time.Time {
+	return TimeFromTimestamp(list.NextTimestamp)
+}
+
+// GetDepositsUntil returns up to limit deposits made at or before until, most recent first.
+// The zero time starts from the most recent deposit. until is sent in the unit of the client's TimestampUnit.
+func (rls *RLSClient) GetDepositsUntil(ctx context.Context, limit int64, until time.Time) (*DepositList, error) {
+	cursor, err := rls.cursorFromTime(ctx, until, rls.probeDeposits)
+	if err != nil {
+		return nil, err
+	}
+	return rls.GetDepositsContext(ctx, limit, cursor)
+}
+
+// ListWithdrawalsUntil returns up to limit withdrawals created at or before until, most recent first.
+// The zero time starts from the most recent withdrawal. until is sent in the unit of the client's TimestampUnit.
+func (rls *RLSClient) ListWithdrawalsUntil(ctx context.Context, limit int64, until time.Time) (*WithdrawalList, error) {
+	cursor, err := rls.cursorFromTime(ctx, until, rls.probeWithdrawals)
+	if err != nil {
+		return nil, err
+	}
+	return rls.ListWithdrawalsContext(ctx, limit, cursor)
+}
+
+// GetInvoicesUntil returns up to limit deposit invoices created at or before until, most recent first.
+// The zero time starts from the most recent invoice. until is sent in the unit of the client's TimestampUnit.
+func (rls *RLSClient) GetInvoicesUntil(ctx context.Context, limit int64, until time.Time) (*InvoiceList, error) {
+	cursor, err := rls.cursorFromTime(ctx, until, rls.probeInvoices)
+	if err != nil {
+		return nil, err
+	}
+	return rls.GetInvoicesContext(ctx, limit, cursor)
+}
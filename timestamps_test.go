@@ -0,0 +1,82 @@
+package rls
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTimestampConversions(t *testing.T) {
+	at := time.Unix(1700000000, 500000000)
+	if got := TimestampFromTimeIn(at, TimestampSeconds); got != 1700000000 {
+		t.Errorf("seconds: got %d", got)
+	}
+	if got := TimestampFromTimeIn(at, TimestampMilliseconds); got != 1700000000500 {
+		t.Errorf("milliseconds: got %d", got)
+	}
+	if TimestampFromTime(time.Time{}) != 0 || !TimeFromTimestamp(0).IsZero() {
+		t.Error("zero time not mapped to 0")
+	}
+	for _, ts := range []int64{1700000000, 1700000000500} {
+		if got := TimestampFromTimeIn(TimeFromTimestamp(ts), timestampUnitOf(ts)); got != ts {
+			t.Errorf("round trip of %d gave %d", ts, got)
+		}
+	}
+}
+
+// cursorRecorder serves withdrawals with the given timestamps and records the next_timestamp of each call
+func cursorRecorder(timestamps []int64, cursors *[]string) http.HandlerFunc {
+	var limits []int64
+	serve := pagedWithdrawals(timestamps, &limits)
+	return func(w http.ResponseWriter, r *http.Request) {
+		*cursors = append(*cursors, r.URL.Query().Get("next_timestamp"))
+		serve(w, r)
+	}
+}
+
+func TestCursorUnits(t *testing.T) {
+	until := time.Unix(1700000040, 0)
+	tests := []struct {
+		name       string
+		timestamps []int64
+		unit       TimestampUnit
+		cursors    []string
+		want       int
+	}{
+		{"detected seconds", []int64{1700000050, 1700000040, 1700000030}, TimestampAuto, []string{"", "1700000040"}, 2},
+		{"detected milliseconds", []int64{1700000050000, 1700000040000, 1700000030000}, TimestampAuto, []string{"", "1700000040000"}, 2},
+		{"configured milliseconds", []int64{1700000050000, 1700000040000, 1700000030000}, TimestampMilliseconds, []string{"1700000040000"}, 2},
+	}
+	for _, tt := range tests {
+		var cursors []string
+		client := newTestClient(t, cursorRecorder(tt.timestamps, &cursors))
+		client.TimestampUnit = tt.unit
+		list, err := client.ListWithdrawalsUntil(context.Background(), 10, until)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(list.Withdrawals) != tt.want {
+			t.Errorf("%s: got %d withdrawals, want %d", tt.name, len(list.Withdrawals), tt.want)
+		}
+		if len(cursors) != len(tt.cursors) || cursors[len(cursors)-1] != tt.cursors[len(tt.cursors)-1] {
+			t.Errorf("%s: sent cursors %q, want %q", tt.name, cursors, tt.cursors)
+		}
+	}
+}
+
+func TestWithdrawalsBetweenMilliseconds(t *testing.T) {
+	var limits []int64
+	client := newTestClient(t, pagedWithdrawals([]int64{1700000050000, 1700000040500, 1700000030000, 1700000020000}, &limits))
+	withdrawals, err := client.WithdrawalsBetween(context.Background(), time.Unix(1700000025, 0), time.Unix(1700000041, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int64
+	for _, wd := range withdrawals {
+		got = append(got, wd.Timestamp)
+	}
+	if want := []int64{1700000030000, 1700000040500}; !equalInt64s(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
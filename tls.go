@@ -0,0 +1,130 @@
+package rls
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrPinMismatch is returned when no certificate presented by the server matches a pinned SPKI hash
+var ErrPinMismatch = errors.New("rls: server certificate does not match pinned public key")
+
+// spkiPinPrefix is the optional prefix of pinned SPKI hashes, as used by curl's --pinnedpubkey
+const spkiPinPrefix = "sha256//"
+
+// TLSOptions configures the TLS connection to RLS. The server certificate is verified against the
+// system roots unless CAFile is set.
+type TLSOptions struct {
+	// CertFile and KeyFile are the PEM client certificate and key used for mutual TLS. Both or neither must be set
+	CertFile string
+	KeyFile  string
+	// CertReloader, if set, provides the client certificate instead of CertFile and KeyFile,
+	// picking up rotated certificates without restarting
+	CertReloader *CertReloader
+	// CAFile is a PEM bundle of the CAs trusted to verify the server, instead of the system roots
+	CAFile string
+	// PinnedSPKI are base64 SHA-256 hashes of a public key the server's certificate chain must contain,
+	// optionally prefixed with "sha256//". See SPKIHash.
+	PinnedSPKI []string
+	// ServerName overrides the name the server certificate is verified against
+	ServerName string
+	// InsecureSkipVerify disables verification of the server certificate. Pins are then checked
+	// against the leaf certificate only. It must only be used for testing.
+	InsecureSkipVerify bool
+}
+
+// TLSPathFiles returns the certificate and key paths of the <tlsPath>.cert and <tlsPath>.key convention
+func TLSPathFiles(tlsPath string) (certFile, keyFile string) {
+	return tlsPath + ".cert", tlsPath + ".key"
+}
+
+// SPKIHash returns the base64 SHA-256 hash of the certificate's SubjectPublicKeyInfo, as used by PinnedSPKI
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Config returns the tls.Config described by the options
+func (o TLSOptions) Config() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+
+	if o.CertReloader == nil && (o.CertFile == "") != (o.KeyFile == "") {
+		return nil, errors.New("invalid tls options : client certificate and key must be set together")
+	}
+	if o.CertReloader != nil {
+		cfg.GetClientCertificate = o.CertReloader.GetClientCertificate
+	} else if o.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate %s : %w", o.CertFile, err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle : %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("failed to read CA bundle %s : no PEM certificates found", o.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if len(o.PinnedSPKI) > 0 {
+		pins := make(map[string]bool, len(o.PinnedSPKI))
+		for _, pin := range o.PinnedSPKI {
+			pin = strings.TrimPrefix(strings.TrimSpace(pin), spkiPinPrefix)
+			if b, err := base64.StdEncoding.DecodeString(pin); err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("invalid tls options : pin %q is not a base64 SHA-256 hash", pin)
+			}
+			pins[pin] = true
+		}
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyPins(cs, pins)
+		}
+	}
+	return cfg, nil
+}
+
+// verifyPins checks that a certificate of a verified chain has a pinned public key. When verification
+// is disabled only the leaf is checked, since the rest of the presented chain is unauthenticated and
+// anyone can append a pinned certificate to it.
+func verifyPins(cs tls.ConnectionState, pins map[string]bool) error {
+	chains := cs.VerifiedChains
+	if len(chains) == 0 && len(cs.PeerCertificates) > 0 {
+		chains = [][]*x509.Certificate{cs.PeerCertificates[:1]}
+	}
+	for _, chain := range chains {
+		for _, cert := range chain {
+			if pins[SPKIHash(cert)] {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w : %s", ErrPinMismatch, cs.ServerName)
+}
+
+// WithTLSOptions sets the TLS configuration of the default transport from opts
+func WithTLSOptions(opts TLSOptions) Option {
+	return func(o *clientOptions) error {
+		tlsConfig, err := opts.Config()
+		if err != nil {
+			return err
+		}
+		o.tlsConfig = tlsConfig
+		o.transportTuned = true
+		return nil
+	}
+}
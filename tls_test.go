@@ -0,0 +1,113 @@
+package rls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testCertificate returns a self-signed certificate with a fresh key
+func testCertificate(t *testing.T, name string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestVerifyPins(t *testing.T) {
+	leaf := testCertificate(t, "leaf")
+	intermediate := testCertificate(t, "intermediate")
+	pinned := testCertificate(t, "pinned")
+	pins := map[string]bool{SPKIHash(pinned): true}
+
+	tests := []struct {
+		name string
+		cs   tls.ConnectionState
+		ok   bool
+	}{
+		{"verified chain with pin", tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf, pinned}}}, true},
+		{"verified chain without pin", tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf, intermediate}}}, false},
+		{"unverified pinned leaf", tls.ConnectionState{PeerCertificates: []*x509.Certificate{pinned, intermediate}}, true},
+		{"unverified appended pin", tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf, pinned}}, false},
+		{"no certificates", tls.ConnectionState{}, false},
+	}
+	for _, tt := range tests {
+		err := verifyPins(tt.cs, pins)
+		if tt.ok && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrPinMismatch) {
+			t.Errorf("%s: expected a pin mismatch, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestTLSOptionsConfig(t *testing.T) {
+	for _, opts := range []TLSOptions{
+		{CertFile: "client.cert"},
+		{PinnedSPKI: []string{"not base64!"}},
+		{PinnedSPKI: []string{"sha256//c2hvcnQ="}},
+		{CAFile: "missing.pem"},
+	} {
+		if _, err := opts.Config(); err == nil {
+			t.Errorf("expected an error for %+v", opts)
+		}
+	}
+}
+
+func TestTLSPinnedConnection(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	serverPin := "sha256//" + SPKIHash(srv.Certificate())
+	otherPin := SPKIHash(testCertificate(t, "other"))
+
+	tests := []struct {
+		pin string
+		ok  bool
+	}{
+		{serverPin, true},
+		{otherPin, false},
+	}
+	for _, tt := range tests {
+		cfg, err := TLSOptions{PinnedSPKI: []string{tt.pin}, InsecureSkipVerify: true}.Config()
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+		resp, err := client.Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if tt.ok != (err == nil) {
+			t.Errorf("pin %s: got %v", tt.pin, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrPinMismatch) {
+			t.Errorf("pin %s: expected a pin mismatch, got %v", tt.pin, err)
+		}
+	}
+}
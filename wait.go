@@ -0,0 +1,175 @@
+package rls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Defaults of WaitOptions
+const (
+	DefaultWaitPollInterval    = time.Second
+	DefaultWaitMaxPollInterval = 30 * time.Second
+)
+
+// ErrWithdrawalFailed is matched by the *WithdrawalFailedError returned when a withdrawal ends in FAIL
+var ErrWithdrawalFailed = errors.New("rls: withdrawal failed")
+
+// WithdrawalFailedError is returned by WaitForWithdrawal when the withdrawal ends in FAIL
+type WithdrawalFailedError struct {
+	Withdrawal *Withdrawal
+}
+
+func (e *WithdrawalFailedError) Error() string {
+	return fmt.Sprintf("withdrawal %s failed", e.Withdrawal.ID)
+}
+
+// Is makes errors.Is(err, ErrWithdrawalFailed) true
+func (e *WithdrawalFailedError) Is(target error) bool {
+	return target == ErrWithdrawalFailed
+}
+
+// WaitOptions configures WaitForWithdrawal
+type WaitOptions struct {
+	// PollInterval is the delay before the second poll, growing exponentially up to MaxPollInterval.
+	// Defaults to DefaultWaitPollInterval
+	PollInterval time.Duration
+	// MaxPollInterval caps the delay between polls. Defaults to DefaultWaitMaxPollInterval
+	MaxPollInterval time.Duration
+	// Timeout bounds the whole wait in addition to the context. 0 disables it
+	Timeout time.Duration
+	// Events, if set, delivers verified webhook events. A WITHDRAWAL event for the awaited withdrawal
+	// triggers an immediate poll, so the outcome is seen as soon as RLS reports it. See WebhookDispatcher.
+	Events <-chan WebhookEvent
+	// OnUpdate, if set, is called with every withdrawal polled
+	OnUpdate func(*Withdrawal)
+}
+
+// WaitForWithdrawal polls the withdrawal withdrawalID until it reaches a terminal state and returns it.
+// If the withdrawal ends in FAIL, it is returned along with a *WithdrawalFailedError. Ambiguous errors
+// while polling, such as timeouts and 5xx responses, are retried at the next poll.
+func (rls *RLSClient) WaitForWithdrawal(ctx context.Context, withdrawalID string, opts WaitOptions) (*Withdrawal, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	backoff := &RetryPolicy{
+		InitialBackoff: opts.PollInterval,
+		MaxBackoff:     opts.MaxPollInterval,
+		Multiplier:     2,
+	}
+	if backoff.InitialBackoff <= 0 {
+		backoff.InitialBackoff = DefaultWaitPollInterval
+	}
+	if backoff.MaxBackoff <= 0 {
+		backoff.MaxBackoff = DefaultWaitMaxPollInterval
+	}
+
+	var last *Withdrawal
+	var lastErr error
+	for poll := 1; ; poll++ {
+		wd, err := rls.GetWithdrawalContext(ctx, withdrawalID)
+		switch {
+		case err == nil:
+			last, lastErr = wd, nil
+			if opts.OnUpdate != nil {
+				opts.OnUpdate(wd)
+			}
+			if wd.State.IsSuccess() {
+				return wd, nil
+			}
+			if wd.State.IsTerminal() {
+				return wd, &WithdrawalFailedError{Withdrawal: wd}
+			}
+		case ctx.Err() == nil && IsAmbiguous(err):
+			lastErr = err
+		default:
+			return last, fmt.Errorf("failed to wait for withdrawal %s : %w", withdrawalID, err)
+		}
+
+		if err := rls.waitForPoll(ctx, withdrawalID, backoff.Backoff(poll), opts.Events); err != nil {
+			if lastErr != nil {
+				err = fmt.Errorf("%w (last poll: %v)", err, lastErr)
+			}
+			return last, fmt.Errorf("failed to wait for withdrawal %s : %w", withdrawalID, err)
+		}
+	}
+}
+
+// waitForPoll waits for d, or for a WITHDRAWAL event about withdrawalID on events
+func (rls *RLSClient) waitForPoll(ctx context.Context, withdrawalID string, d time.Duration, events <-chan WebhookEvent) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				// the event source is gone, fall back to polling only
+				events = nil
+				continue
+			}
+			if event.Type == WebhookTypeWithdrawal && event.ID == withdrawalID {
+				return nil
+			}
+		}
+	}
+}
+
+// WebhookDispatcher fans webhook events out to the waiters subscribed to each object ID, so a
+// single webhook handler can feed many concurrent WaitForWithdrawal calls. It is safe for concurrent use.
+type WebhookDispatcher struct {
+	mu          sync.Mutex
+	subscribers map[string][]chan WebhookEvent
+}
+
+// NewWebhookDispatcher returns an empty WebhookDispatcher
+func NewWebhookDispatcher() *WebhookDispatcher {
+	return &WebhookDispatcher{subscribers: make(map[string][]chan WebhookEvent)}
+}
+
+// Subscribe returns a channel receiving the events about id, and a function to unsubscribe
+func (d *WebhookDispatcher) Subscribe(id string) (<-chan WebhookEvent, func()) {
+	ch := make(chan WebhookEvent, 1)
+	d.mu.Lock()
+	d.subscribers[id] = append(d.subscribers[id], ch)
+	d.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			subs := d.subscribers[id]
+			for i, sub := range subs {
+				if sub == ch {
+					d.subscribers[id] = append(subs[:i:i], subs[i+1:]...)
+					break
+				}
+			}
+			if len(d.subscribers[id]) == 0 {
+				delete(d.subscribers, id)
+			}
+		})
+	}
+}
+
+// Dispatch delivers event to the subscribers of its ID. It never blocks: a subscriber that has not
+// consumed its previous event misses this one, which is harmless as events only trigger a poll.
+// Events must be verified with VerifyWebhookSignature before being dispatched.
+func (d *WebhookDispatcher) Dispatch(event WebhookEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, ch := range d.subscribers[event.ID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package rls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// withdrawalPolls serves GET withdrawal with the successive responses, each a state or an HTTP status,
+// repeating the last one. polls counts the requests.
+func withdrawalPolls(polls *int32, responses ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(polls, 1))
+		if n > len(responses) {
+			n = len(responses)
+		}
+		var status int
+		if _, err := fmt.Sscanf(responses[n-1], "%d", &status); err == nil {
+			w.WriteHeader(status)
+			return
+		}
+		fmt.Fprintf(w, `{"id":"wd_1","amount":1000,"currency":"BTC","state":%q}`, responses[n-1])
+	}
+}
+
+func TestWaitForWithdrawal(t *testing.T) {
+	opts := WaitOptions{PollInterval: time.Millisecond, MaxPollInterval: 2 * time.Millisecond}
+	tests := []struct {
+		name      string
+		responses []string
+		state     WithdrawalState
+		sentinel  error
+		polls     int32
+		updates   int
+	}{
+		{"success", []string{"PENDING", "500", "PENDING", "SUCCESS"}, WithdrawalStateSuccess, nil, 4, 3},
+		{"fail", []string{"PENDING", "FAIL"}, WithdrawalStateFail, ErrWithdrawalFailed, 2, 2},
+		{"not found", []string{"404"}, "", ErrNotFound, 1, 0},
+	}
+	for _, tt := range tests {
+		var polls int32
+		client := newTestClient(t, withdrawalPolls(&polls, tt.responses...))
+		var updates int
+		opts.OnUpdate = func(*Withdrawal) { updates++ }
+
+		wd, err := client.WaitForWithdrawal(context.Background(), "wd_1", opts)
+		if tt.sentinel == nil && err != nil || tt.sentinel != nil && !errors.Is(err, tt.sentinel) {
+			t.Errorf("%s: got error %v", tt.name, err)
+		}
+		if tt.state != "" && (wd == nil || wd.State != tt.state) {
+			t.Errorf("%s: got %+v", tt.name, wd)
+		}
+		if polls != tt.polls || updates != tt.updates {
+			t.Errorf("%s: got %d polls and %d updates, want %d and %d", tt.name, polls, updates, tt.polls, tt.updates)
+		}
+		var failed *WithdrawalFailedError
+		if errors.As(err, &failed) && failed.Withdrawal != wd {
+			t.Errorf("%s: failed error does not carry the withdrawal", tt.name)
+		}
+	}
+}
+
+func TestWaitForWithdrawalTimeout(t *testing.T) {
+	var polls int32
+	client := newTestClient(t, withdrawalPolls(&polls, "PENDING", "503"))
+	wd, err := client.WaitForWithdrawal(context.Background(), "wd_1", WaitOptions{
+		PollInterval: time.Millisecond,
+		Timeout:      50 * time.Millisecond,
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "last poll") {
+		t.Errorf("expected a deadline error with the last poll error, got %v", err)
+	}
+	if wd == nil || wd.State != WithdrawalStatePending {
+		t.Errorf("expected the last withdrawal polled, got %+v", wd)
+	}
+}
+
+func TestWaitForWithdrawalEvents(t *testing.T) {
+	var polls int32
+	client := newTestClient(t, withdrawalPolls(&polls, "PENDING", "SUCCESS"))
+	dispatcher := NewWebhookDispatcher()
+	events, unsubscribe := dispatcher.Subscribe("wd_1")
+	defer unsubscribe()
+
+	go func() {
+		for atomic.LoadInt32(&polls) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		dispatcher.Dispatch(WebhookEvent{ID: "wd_2", Type: WebhookTypeWithdrawal})
+		dispatcher.Dispatch(WebhookEvent{ID: "wd_1", Type: WebhookTypeWithdrawal, State: "SUCCESS"})
+	}()
+
+	// the poll interval is far longer than the test timeout, so only the event can trigger the second poll
+	wd, err := client.WaitForWithdrawal(context.Background(), "wd_1", WaitOptions{
+		PollInterval: time.Hour,
+		Timeout:      5 * time.Second,
+		Events:       events,
+	})
+	if err != nil || wd.State != WithdrawalStateSuccess {
+		t.Errorf("got %+v, %v", wd, err)
+	}
+}
+
+func TestWebhookDispatcher(t *testing.T) {
+	dispatcher := NewWebhookDispatcher()
+	a, unsubscribeA := dispatcher.Subscribe("wd_1")
+	b, unsubscribeB := dispatcher.Subscribe("wd_1")
+	other, unsubscribeOther := dispatcher.Subscribe("wd_2")
+	defer unsubscribeOther()
+
+	event := WebhookEvent{ID: "wd_1", Type: WebhookTypeWithdrawal}
+	dispatcher.Dispatch(event)
+	// a full subscriber must not block dispatch
+	dispatcher.Dispatch(event)
+	if got := <-a; got != event {
+		t.Errorf("got %+v", got)
+	}
+	if got := <-b; got != event {
+		t.Errorf("got %+v", got)
+	}
+	select {
+	case got := <-other:
+		t.Errorf("event for another ID delivered: %+v", got)
+	default:
+	}
+
+	unsubscribeA()
+	unsubscribeA()
+	dispatcher.Dispatch(event)
+	select {
+	case <-a:
+		t.Error("event delivered after unsubscribing")
+	default:
+	}
+	if got := <-b; got != event {
+		t.Errorf("got %+v", got)
+	}
+	unsubscribeB()
+	if len(dispatcher.subscribers) != 1 {
+		t.Errorf("got subscribers %v", dispatcher.subscribers)
+	}
+}
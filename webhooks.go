@@ -2,6 +2,7 @@ package rls
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -20,7 +21,14 @@ type Webhook struct {
 }
 
 // SubscribeToWebhook subscribes to a webhook
+//
+// Deprecated: use SubscribeToWebhookContext
 func (rls *RLSClient) SubscribeToWebhook(callbackURL string) (*Webhook, error) {
+	return rls.SubscribeToWebhookContext(rls.context(), callbackURL)
+}
+
+// SubscribeToWebhookContext subscribes to a webhook
+func (rls *RLSClient) SubscribeToWebhookContext(ctx context.Context, callbackURL string) (*Webhook, error) {
 	data := map[string]string{
 		"url": callbackURL,
 	}
@@ -35,21 +43,35 @@ func (rls *RLSClient) SubscribeToWebhook(callbackURL string) (*Webhook, error) {
 		url,
 		bytes.NewBuffer(body),
 	)
-	return rls.handleWebhookRequest(req, err)
+	return rls.handleWebhookRequest(ctx, OpSubscribeToWebhook, req, err)
 }
 
 // GetSubscribedWebhook queries subscribed webhook
+//
+// Deprecated: use GetSubscribedWebhookContext
 func (rls *RLSClient) GetSubscribedWebhook() (*Webhook, error) {
+	return rls.GetSubscribedWebhookContext(rls.context())
+}
+
+// GetSubscribedWebhookContext queries subscribed webhook
+func (rls *RLSClient) GetSubscribedWebhookContext(ctx context.Context) (*Webhook, error) {
 	req, err := http.NewRequest(
 		http.MethodGet,
 		fmt.Sprintf("%s/accounts/%s/webhooks", rls.BaseURL(), rls.AccountID()),
 		nil,
 	)
-	return rls.handleWebhookRequest(req, err)
+	return rls.handleWebhookRequest(ctx, OpGetSubscribedWebhook, req, err)
 }
 
 // DeleteWebhook deletes the existing webhook
+//
+// Deprecated: use DeleteWebhookContext
 func (rls *RLSClient) DeleteWebhook(callbackURL string) error {
+	return rls.DeleteWebhookContext(rls.context(), callbackURL)
+}
+
+// DeleteWebhookContext deletes the existing webhook
+func (rls *RLSClient) DeleteWebhookContext(ctx context.Context, callbackURL string) error {
 	data := map[string]string{
 		"url": callbackURL,
 	}
@@ -67,16 +89,16 @@ func (rls *RLSClient) DeleteWebhook(callbackURL string) error {
 		return err
 	}
 
-	return rls.sendRequest(req, nil)
+	return rls.sendRequest(ctx, OpDeleteWebhook, req, nil)
 }
 
-func (rls *RLSClient) handleWebhookRequest(req *http.Request, err error) (*Webhook, error) {
+func (rls *RLSClient) handleWebhookRequest(ctx context.Context, op string, req *http.Request, err error) (*Webhook, error) {
 	if err != nil {
 		return nil, err
 	}
 
 	var webhook Webhook
-	err = rls.sendRequest(req, &webhook)
+	err = rls.sendRequest(ctx, op, req, &webhook)
 	if err != nil {
 		return nil, err
 	}
@@ -91,6 +113,7 @@ const (
 	WebhookTypeDeposit    string = "DEPOSIT"
 	WebhookTypeWithdrawal string = "WITHDRAWAL"
 
+	// WebhookState* are the raw event states. Prefer WebhookEvent.WithdrawalState and WebhookEvent.DepositState.
 	WebhookStateSuccess string = "SUCCESS"
 	WebhookStatePending string = "PENDING"
 	WebhookStateFail    string = "FAIL"
@@ -103,6 +126,22 @@ type WebhookEvent struct {
 	State string `json:"state"`
 }
 
+// WithdrawalState returns the state of a WITHDRAWAL event. ok is false for other event types.
+func (e WebhookEvent) WithdrawalState() (state WithdrawalState, ok bool) {
+	if e.Type != WebhookTypeWithdrawal {
+		return "", false
+	}
+	return WithdrawalState(e.State), true
+}
+
+// DepositState returns the state of a DEPOSIT event. ok is false for other event types.
+func (e WebhookEvent) DepositState() (state DepositState, ok bool) {
+	if e.Type != WebhookTypeDeposit {
+		return "", false
+	}
+	return DepositState(e.State), true
+}
+
 type WebhookHeader struct {
 	Timestamp string `json:"timestamp"`
 	Signature string `json:"signature"`
@@ -2,6 +2,7 @@ package rls
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -11,18 +12,22 @@ import (
 type WithdrawalDetail struct {
 	Network  string `json:"network"`
 	Invoice  string `json:"destination"`
-	FeeLimit int64  `json:"fee_limit"`
+	FeeLimit Amount `json:"fee_limit"`
 }
 
 // Withdrawal contains the result of a call that returns a withdrawal
 type Withdrawal struct {
-	Amount    int64            `json:"amount"`
+	// Amount of the withdrawal. For a currency other than BTC, Amount.Sats() is the amount in its base unit
+	Amount    Amount           `json:"amount"`
 	Currency  string           `json:"currency"`
 	Details   WithdrawalDetail `json:"withdrawal_details"`
-	State     string           `json:"state,omitempty"`
+	State     WithdrawalState  `json:"state,omitempty"`
 	ID        string           `json:"id,omitempty"`
-	FeePaid   int64            `json:"fee_paid,omitempty"`
+	FeePaid   Amount           `json:"fee_paid,omitempty"`
 	Timestamp int64            `json:"timestamp,omitempty"`
+	// IdempotencyKey is sent in the Idempotency-Key header when the withdrawal is submitted.
+	// It is generated by NewWithdrawalContext if empty.
+	IdempotencyKey string `json:"-"`
 }
 
 type WithdrawalList struct {
@@ -41,7 +46,7 @@ func (wd *Withdrawal) Network() string {
 }
 
 // FeeLimit returns Withdrawal Detail's Fee Limit
-func (wd *Withdrawal) FeeLimit() int64 {
+func (wd *Withdrawal) FeeLimit() Amount {
 	return wd.Details.FeeLimit
 }
 
@@ -50,38 +55,36 @@ const (
 	LN string = "LN"
 	// BTC is the default and only currency
 	BTC string = "BTC"
-	// DefaultFeeLimit is the default fee limit of this client
-	DefaultFeeLimit int64 = 300
+	// DefaultFeeLimit is the fee limit of DefaultFeePolicy
+	DefaultFeeLimit Amount = 300 * Satoshi
 )
 
-func (rls *RLSClient) handleWithdrawal(req *http.Request, err error) (*Withdrawal, error) {
+func (rls *RLSClient) handleWithdrawal(ctx context.Context, op string, req *http.Request, err error) (*Withdrawal, error) {
 	if err != nil {
 		return nil, err
 	}
 
 	var withdrawal Withdrawal
-	err = rls.sendRequest(req, &withdrawal)
+	err = rls.sendRequest(ctx, op, req, &withdrawal)
 	if err != nil {
 		return nil, err
 	}
 	return &withdrawal, nil
 }
 
-// NewWithdrawal returns a Withdrawal object to be passed to SubmitWithdrawal
+// NewWithdrawal returns a Withdrawal object for amount sats to be passed to SubmitWithdrawal, with the fee limit
+// chosen by DefaultFeePolicy
 func NewWithdrawal(amount int64, invoice string) *Withdrawal {
-	return &Withdrawal{
-		Amount:   amount,
-		Currency: BTC,
-		Details: WithdrawalDetail{
-			Invoice:  invoice,
-			FeeLimit: DefaultFeeLimit,
-			Network:  LN,
-		},
-	}
+	return NewWithdrawalWithFeePolicy(Sats(amount), invoice, DefaultFeePolicy)
 }
 
-// NewWithdrawalWithFeeLimit returns a Withdrawal object with a defined fee_limit to be passed to SubmitWithdrawal
+// NewWithdrawalWithFeeLimit returns a Withdrawal object for amount sats with a defined fee_limit in sats to be passed to SubmitWithdrawal
 func NewWithdrawalWithFeeLimit(amount int64, invoice string, feeLimit int64) *Withdrawal {
+	return NewWithdrawalFromAmount(Sats(amount), invoice, Sats(feeLimit))
+}
+
+// NewWithdrawalFromAmount returns a Withdrawal object for amount with a defined fee limit to be passed to SubmitWithdrawal
+func NewWithdrawalFromAmount(amount Amount, invoice string, feeLimit Amount) *Withdrawal {
 	return &Withdrawal{
 		Amount:   amount,
 		Currency: BTC,
@@ -93,20 +96,73 @@ func NewWithdrawalWithFeeLimit(amount int64, invoice string, feeLimit int64) *Wi
 	}
 }
 
+// NewWithdrawalWithFeePolicy returns a Withdrawal object with the fee limit chosen by policy for amount,
+// to be passed to SubmitWithdrawal
+func NewWithdrawalWithFeePolicy(amount Amount, invoice string, policy FeePolicy) *Withdrawal {
+	return NewWithdrawalFromAmount(amount, invoice, policy.FeeLimit(amount))
+}
+
+// NewWithdrawalWithCurrency returns a Withdrawal object paying invoice from the balance in currency,
+// to be passed to SubmitWithdrawal. amount and feeLimit are in the currency's base unit, sats for BTC.
+func NewWithdrawalWithCurrency(currency string, amount int64, invoice string, feeLimit int64) *Withdrawal {
+	withdrawal := NewWithdrawalWithFeeLimit(amount, invoice, feeLimit)
+	withdrawal.Currency = currency
+	return withdrawal
+}
+
 // NewWithdrawal initiates a withdrawal from RLS API by paying a specific invoice
+//
+// Deprecated: use NewWithdrawalContext
 func (rls *RLSClient) NewWithdrawal(withdrawal *Withdrawal) (*Withdrawal, error) {
+	return rls.NewWithdrawalContext(rls.context(), withdrawal)
+}
+
+// NewWithdrawalContext initiates a withdrawal from RLS API by paying a specific invoice.
+// If withdrawal.IdempotencyKey is empty, a new key is generated and stored on withdrawal,
+// so resubmitting the same withdrawal can never pay twice. A currency other than BTC is validated
+// against the account's currencies first, and amounts that are not whole sats are rejected before sending.
+func (rls *RLSClient) NewWithdrawalContext(ctx context.Context, withdrawal *Withdrawal) (*Withdrawal, error) {
+	if err := checkWholeSats("amount", withdrawal.Amount); err != nil {
+		return nil, fmt.Errorf("failed to create withdrawal : %w", err)
+	}
+	if err := checkWholeSats("fee limit", withdrawal.Details.FeeLimit); err != nil {
+		return nil, fmt.Errorf("failed to create withdrawal : %w", err)
+	}
+	if err := rls.ValidateCurrency(ctx, withdrawal.Currency); err != nil {
+		return nil, err
+	}
+	if withdrawal.IdempotencyKey == "" {
+		withdrawal.IdempotencyKey = NewIdempotencyKey()
+	}
+
 	body, err := json.Marshal(withdrawal)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to create withdrawal : %w : %v", ErrInvalidRequest, err)
 	}
 
 	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/accounts/%s/withdrawals", rls.BaseURL(), rls.AccountID()), bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create withdrawal : %w : %v", ErrInvalidRequest, err)
+	}
+	req.Header.Set(IdempotencyKeyHeader, withdrawal.IdempotencyKey)
 
-	return rls.handleWithdrawal(req, err)
+	wd, err := rls.handleWithdrawal(ctx, OpNewWithdrawal, req, nil)
+	if err != nil {
+		return nil, err
+	}
+	wd.IdempotencyKey = withdrawal.IdempotencyKey
+	return wd, nil
 }
 
 // GetWithdrawal returns a withdrawal based on the passed withdrawal_id
+//
+// Deprecated: use GetWithdrawalContext
 func (rls *RLSClient) GetWithdrawal(withdrawalID string) (*Withdrawal, error) {
+	return rls.GetWithdrawalContext(rls.context(), withdrawalID)
+}
+
+// GetWithdrawalContext returns a withdrawal based on the passed withdrawal_id
+func (rls *RLSClient) GetWithdrawalContext(ctx context.Context, withdrawalID string) (*Withdrawal, error) {
 	req, err := http.NewRequest(
 		http.MethodGet,
 		fmt.Sprintf("%s/accounts/%s/withdrawals/%s",
@@ -115,10 +171,18 @@ func (rls *RLSClient) GetWithdrawal(withdrawalID string) (*Withdrawal, error) {
 			withdrawalID),
 		nil,
 	)
-	return rls.handleWithdrawal(req, err)
+	return rls.handleWithdrawal(ctx, OpGetWithdrawal, req, err)
 }
 
+// ListWithdrawals returns a list of recent withdrawals
+//
+// Deprecated: use ListWithdrawalsContext
 func (rls *RLSClient) ListWithdrawals(limit int64, nextTimestamp int64) (*WithdrawalList, error) {
+	return rls.ListWithdrawalsContext(rls.context(), limit, nextTimestamp)
+}
+
+// ListWithdrawalsContext returns a list of recent withdrawals
+func (rls *RLSClient) ListWithdrawalsContext(ctx context.Context, limit int64, nextTimestamp int64) (*WithdrawalList, error) {
 	req, err := http.NewRequest(
 		http.MethodGet,
 		fmt.Sprintf("%s/accounts/%s/withdrawals",
@@ -127,6 +191,9 @@ func (rls *RLSClient) ListWithdrawals(limit int64, nextTimestamp int64) (*Withdr
 		),
 		nil,
 	)
+	if err != nil {
+		return nil, err
+	}
 
 	// Add query params
 	query := req.URL.Query()
@@ -137,9 +204,13 @@ func (rls *RLSClient) ListWithdrawals(limit int64, nextTimestamp int64) (*Withdr
 	req.URL.RawQuery = query.Encode()
 
 	var withdrawals WithdrawalList
-	err = rls.sendRequest(req, &withdrawals)
+	err = rls.sendRequest(ctx, OpListWithdrawals, req, &withdrawals)
 	if err != nil {
 		return nil, err
 	}
+	rls.observeTimestamps(withdrawals.NextTimestamp)
+	if len(withdrawals.Withdrawals) > 0 {
+		rls.observeTimestamps(withdrawals.Withdrawals[0].Timestamp)
+	}
 	return &withdrawals, nil
 }